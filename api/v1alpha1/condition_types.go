@@ -0,0 +1,48 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition describes one aspect of the current state of a resource.
+// It follows the same conventions of the conditions used by the
+// kubernetes core resources.
+type Condition struct {
+	// Type of the condition, in CamelCase
+	Type string `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// The .metadata.generation the condition was set upon
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Last time the condition transitioned from one status to another
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// Programmatic identifier, in CamelCase, of the reason for the
+	// condition's last transition
+	Reason string `json:"reason"`
+
+	// Human readable message with details about the transition
+	// +optional
+	Message string `json:"message,omitempty"`
+}
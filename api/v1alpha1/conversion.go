@@ -0,0 +1,142 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flavio/organization-operator/api/v1beta1"
+)
+
+// ConversionDataAnnotation holds the serialized spec and status of the hub
+// version of an object converted to v1alpha1. It allows to restore the
+// fields that cannot be represented by v1alpha1 when the object is converted
+// back to the hub version.
+const ConversionDataAnnotation = "k8s.suse.com/conversion-data"
+
+// conversionData is the content of the ConversionDataAnnotation
+type conversionData struct {
+	Spec   json.RawMessage `json:"spec,omitempty"`
+	Status json.RawMessage `json:"status,omitempty"`
+}
+
+// marshalConversionData stores the spec and status of the hub object inside
+// of the annotations of dst
+func marshalConversionData(dst metav1.Object, spec, status interface{}) error {
+	specData, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	statusData, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(conversionData{
+		Spec:   specData,
+		Status: statusData,
+	})
+	if err != nil {
+		return err
+	}
+
+	annotations := dst.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[ConversionDataAnnotation] = string(data)
+	dst.SetAnnotations(annotations)
+
+	return nil
+}
+
+// unmarshalConversionData restores the spec and status of the hub object
+// saved by marshalConversionData, then removes the annotation from dst. It
+// does nothing when src was not produced by a conversion.
+func unmarshalConversionData(src, dst metav1.Object, spec, status interface{}) error {
+	raw, found := src.GetAnnotations()[ConversionDataAnnotation]
+	if !found {
+		return nil
+	}
+
+	annotations := map[string]string{}
+	for key, value := range dst.GetAnnotations() {
+		if key != ConversionDataAnnotation {
+			annotations[key] = value
+		}
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	dst.SetAnnotations(annotations)
+
+	data := conversionData{}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		return err
+	}
+	if len(data.Spec) > 0 {
+		if err := json.Unmarshal(data.Spec, spec); err != nil {
+			return err
+		}
+	}
+	if len(data.Status) > 0 {
+		if err := json.Unmarshal(data.Status, status); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func convertConditionsTo(conditions []Condition) []v1beta1.Condition {
+	if conditions == nil {
+		return nil
+	}
+
+	converted := []v1beta1.Condition{}
+	for _, condition := range conditions {
+		converted = append(converted, v1beta1.Condition{
+			Type:               condition.Type,
+			Status:             condition.Status,
+			ObservedGeneration: condition.ObservedGeneration,
+			LastTransitionTime: condition.LastTransitionTime,
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+		})
+	}
+	return converted
+}
+
+func convertConditionsFrom(conditions []v1beta1.Condition) []Condition {
+	if conditions == nil {
+		return nil
+	}
+
+	converted := []Condition{}
+	for _, condition := range conditions {
+		converted = append(converted, Condition{
+			Type:               condition.Type,
+			Status:             condition.Status,
+			ObservedGeneration: condition.ObservedGeneration,
+			LastTransitionTime: condition.LastTransitionTime,
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+		})
+	}
+	return converted
+}
@@ -0,0 +1,354 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flavio/organization-operator/api/v1beta1"
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+var transitionTime = metav1.NewTime(time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC))
+
+func TestSpaceRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		hub  v1beta1.Space
+	}{
+		{
+			name: "empty",
+			hub: v1beta1.Space{
+				ObjectMeta: metav1.ObjectMeta{Name: "space", Namespace: "acme-spaces"},
+			},
+		},
+		{
+			name: "fields known by v1alpha1",
+			hub: v1beta1.Space{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "space",
+					Namespace:   "acme-spaces",
+					Annotations: map[string]string{"owner": "team-a"},
+				},
+				Spec: v1beta1.SpaceSpec{
+					Admins:       []string{"alice"},
+					EditorGroups: []string{"developers"},
+				},
+				Status: v1beta1.SpaceStatus{
+					Namespace: "acme-space",
+					Phase:     v1beta1.SpacePhaseReady,
+					Conditions: []v1beta1.Condition{{
+						Type:               v1beta1.SpaceConditionNamespaceReady,
+						Status:             corev1.ConditionTrue,
+						LastTransitionTime: transitionTime,
+						Reason:             "Reconciled",
+					}},
+					ObservedGeneration: 3,
+				},
+			},
+		},
+		{
+			name: "fields unknown to v1alpha1",
+			hub: v1beta1.Space{
+				ObjectMeta: metav1.ObjectMeta{Name: "space", Namespace: "acme-spaces"},
+				Spec: v1beta1.SpaceSpec{
+					Admins:               []string{"alice"},
+					AdminServiceAccounts: []string{"ci/deployer"},
+					NamespaceLabels:      map[string]string{"tier": "gold"},
+					NamespaceAnnotations: map[string]string{"contact": "alice@example.com"},
+					Quota: &v1beta1.SpaceQuota{
+						Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("2")},
+					},
+					NetworkIsolation: v1beta1.NetworkIsolationSpace,
+					DeletionPolicy:   v1beta1.SpaceDeletionPolicyRetain,
+					AdoptNamespace:   "legacy",
+					RoleBindings: []v1beta1.SpaceRoleBinding{{
+						Name:        "monitoring",
+						ClusterRole: "view",
+						Groups:      []string{"sre"},
+					}},
+				},
+				Status: v1beta1.SpaceStatus{
+					Namespace:           "legacy",
+					NamespaceFinalizers: []string{"kubernetes"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spoke := &Space{}
+			if err := spoke.ConvertFrom(test.hub.DeepCopy()); err != nil {
+				t.Fatalf("ConvertFrom failed: %v", err)
+			}
+			if _, found := spoke.GetAnnotations()[ConversionDataAnnotation]; !found {
+				t.Fatalf("the %s annotation is missing", ConversionDataAnnotation)
+			}
+
+			hub := &v1beta1.Space{}
+			if err := spoke.ConvertTo(hub); err != nil {
+				t.Fatalf("ConvertTo failed: %v", err)
+			}
+			if !equality.Semantic.DeepEqual(&test.hub, hub) {
+				t.Errorf("round trip changed the Space:\nexpected %+v\ngot      %+v", test.hub, *hub)
+			}
+		})
+	}
+}
+
+func TestSpaceChangedThroughV1alpha1(t *testing.T) {
+	original := &v1beta1.Space{
+		ObjectMeta: metav1.ObjectMeta{Name: "space", Namespace: "acme-spaces"},
+		Spec: v1beta1.SpaceSpec{
+			Admins:         []string{"alice"},
+			DeletionPolicy: v1beta1.SpaceDeletionPolicyOrphan,
+		},
+	}
+
+	spoke := &Space{}
+	if err := spoke.ConvertFrom(original.DeepCopy()); err != nil {
+		t.Fatalf("ConvertFrom failed: %v", err)
+	}
+	spoke.Spec.Admins = []string{"bob"}
+
+	hub := &v1beta1.Space{}
+	if err := spoke.ConvertTo(hub); err != nil {
+		t.Fatalf("ConvertTo failed: %v", err)
+	}
+	if !equality.Semantic.DeepEqual(hub.Spec.Admins, []string{"bob"}) {
+		t.Errorf("the change made through v1alpha1 has been lost: %v", hub.Spec.Admins)
+	}
+	if hub.Spec.DeletionPolicy != v1beta1.SpaceDeletionPolicyOrphan {
+		t.Errorf("the deletion policy has been lost: %q", hub.Spec.DeletionPolicy)
+	}
+	if _, found := hub.GetAnnotations()[ConversionDataAnnotation]; found {
+		t.Errorf("the %s annotation has been kept by the hub version", ConversionDataAnnotation)
+	}
+}
+
+func TestSpaceWithoutConversionData(t *testing.T) {
+	spoke := &Space{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "space",
+			Namespace:   "acme-spaces",
+			Annotations: map[string]string{"owner": "team-a"},
+		},
+		Spec: SpaceSpec{
+			Admins:       []string{"alice"},
+			ViewerGroups: []string{"auditors"},
+		},
+	}
+
+	hub := &v1beta1.Space{}
+	if err := spoke.ConvertTo(hub); err != nil {
+		t.Fatalf("ConvertTo failed: %v", err)
+	}
+	expected := &v1beta1.Space{
+		ObjectMeta: spoke.ObjectMeta,
+		Spec: v1beta1.SpaceSpec{
+			Admins:       []string{"alice"},
+			ViewerGroups: []string{"auditors"},
+		},
+	}
+	if !equality.Semantic.DeepEqual(expected, hub) {
+		t.Errorf("unexpected conversion:\nexpected %+v\ngot      %+v", *expected, *hub)
+	}
+}
+
+func TestOrganizationRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		hub  v1beta1.Organization
+	}{
+		{
+			name: "empty",
+			hub: v1beta1.Organization{
+				ObjectMeta: metav1.ObjectMeta{Name: "acme"},
+			},
+		},
+		{
+			name: "fields known by v1alpha1",
+			hub: v1beta1.Organization{
+				ObjectMeta: metav1.ObjectMeta{Name: "acme"},
+				Spec: v1beta1.OrganizationSpec{
+					AdminGroups:            []string{"admins"},
+					DefaultNamespaceLabels: map[string]string{"cost-center": "42"},
+				},
+				Status: v1beta1.OrganizationStatus{
+					SpacesNamespace: "acme-spaces",
+					SpaceCount:      1,
+					Spaces: []v1beta1.SpaceSummary{{
+						Name:  "space",
+						Phase: v1beta1.SpacePhaseReady,
+					}},
+					Phase: v1beta1.OrganizationPhaseReady,
+				},
+			},
+		},
+		{
+			name: "fields unknown to v1alpha1",
+			hub: v1beta1.Organization{
+				ObjectMeta: metav1.ObjectMeta{Name: "acme"},
+				Spec: v1beta1.OrganizationSpec{
+					Admins:                      []string{"alice"},
+					DefaultNamespaceAnnotations: map[string]string{"contact": "alice@example.com"},
+					ClusterRoles:                &v1beta1.ClusterRoleMapping{Admin: "acme-admin"},
+					DeletionPolicy:              v1beta1.OrganizationDeletionPolicyBlock,
+					Budget:                      corev1.ResourceList{corev1.ResourceRequestsMemory: resource.MustParse("8Gi")},
+				},
+				Status: v1beta1.OrganizationStatus{
+					AllocatedBudget: corev1.ResourceList{corev1.ResourceRequestsMemory: resource.MustParse("2Gi")},
+					AvailableBudget: corev1.ResourceList{corev1.ResourceRequestsMemory: resource.MustParse("6Gi")},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spoke := &Organization{}
+			if err := spoke.ConvertFrom(test.hub.DeepCopy()); err != nil {
+				t.Fatalf("ConvertFrom failed: %v", err)
+			}
+			if _, found := spoke.GetAnnotations()[ConversionDataAnnotation]; !found {
+				t.Fatalf("the %s annotation is missing", ConversionDataAnnotation)
+			}
+
+			hub := &v1beta1.Organization{}
+			if err := spoke.ConvertTo(hub); err != nil {
+				t.Fatalf("ConvertTo failed: %v", err)
+			}
+			if !equality.Semantic.DeepEqual(&test.hub, hub) {
+				t.Errorf("round trip changed the Organization:\nexpected %+v\ngot      %+v", test.hub, *hub)
+			}
+		})
+	}
+}
+
+func TestSpaceDefault(t *testing.T) {
+	tests := []struct {
+		name           string
+		hub            v1beta1.Space
+		deletionPolicy v1beta1.SpaceDeletionPolicy
+	}{
+		{
+			name: "new Space",
+			hub: v1beta1.Space{
+				ObjectMeta: metav1.ObjectMeta{Name: "space", Namespace: "acme-spaces"},
+			},
+			deletionPolicy: "",
+		},
+		{
+			name: "adopted Namespace",
+			hub: v1beta1.Space{
+				ObjectMeta: metav1.ObjectMeta{Name: "space", Namespace: "acme-spaces"},
+				Spec:       v1beta1.SpaceSpec{AdoptNamespace: "legacy"},
+			},
+			deletionPolicy: v1beta1.SpaceDeletionPolicyRetain,
+		},
+		{
+			name: "adopted Namespace with a deletion policy",
+			hub: v1beta1.Space{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "space",
+					Namespace:  "acme-spaces",
+					Finalizers: []string{common.SpaceFinalizer},
+				},
+				Spec: v1beta1.SpaceSpec{
+					AdoptNamespace: "legacy",
+					DeletionPolicy: v1beta1.SpaceDeletionPolicyDelete,
+				},
+			},
+			deletionPolicy: v1beta1.SpaceDeletionPolicyDelete,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spoke := &Space{}
+			if err := spoke.ConvertFrom(test.hub.DeepCopy()); err != nil {
+				t.Fatalf("ConvertFrom failed: %v", err)
+			}
+			spoke.Default()
+
+			hub := &v1beta1.Space{}
+			if err := spoke.ConvertTo(hub); err != nil {
+				t.Fatalf("ConvertTo failed: %v", err)
+			}
+			if !reflect.DeepEqual(hub.GetFinalizers(), []string{common.SpaceFinalizer}) {
+				t.Errorf("expected the %s finalizer, got %v", common.SpaceFinalizer, hub.GetFinalizers())
+			}
+			if hub.Spec.DeletionPolicy != test.deletionPolicy {
+				t.Errorf("expected deletion policy %q, got %q", test.deletionPolicy, hub.Spec.DeletionPolicy)
+			}
+		})
+	}
+}
+
+func TestOrganizationDefault(t *testing.T) {
+	tests := []struct {
+		name           string
+		hub            v1beta1.Organization
+		deletionPolicy v1beta1.OrganizationDeletionPolicy
+	}{
+		{
+			name:           "new Organization",
+			hub:            v1beta1.Organization{ObjectMeta: metav1.ObjectMeta{Name: "acme"}},
+			deletionPolicy: v1beta1.OrganizationDeletionPolicyCascade,
+		},
+		{
+			name: "deletion policy set",
+			hub: v1beta1.Organization{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "acme",
+					Finalizers: []string{common.OrganizationFinalizer},
+				},
+				Spec: v1beta1.OrganizationSpec{DeletionPolicy: v1beta1.OrganizationDeletionPolicyBlock},
+			},
+			deletionPolicy: v1beta1.OrganizationDeletionPolicyBlock,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spoke := &Organization{}
+			if err := spoke.ConvertFrom(test.hub.DeepCopy()); err != nil {
+				t.Fatalf("ConvertFrom failed: %v", err)
+			}
+			spoke.Default()
+
+			hub := &v1beta1.Organization{}
+			if err := spoke.ConvertTo(hub); err != nil {
+				t.Fatalf("ConvertTo failed: %v", err)
+			}
+			if !reflect.DeepEqual(hub.GetFinalizers(), []string{common.OrganizationFinalizer}) {
+				t.Errorf("expected the %s finalizer, got %v", common.OrganizationFinalizer, hub.GetFinalizers())
+			}
+			if hub.Spec.DeletionPolicy != test.deletionPolicy {
+				t.Errorf("expected deletion policy %q, got %q", test.deletionPolicy, hub.Spec.DeletionPolicy)
+			}
+		})
+	}
+}
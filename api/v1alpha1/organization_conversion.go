@@ -0,0 +1,86 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/flavio/organization-operator/api/v1beta1"
+)
+
+var _ conversion.Convertible = &Organization{}
+
+// ConvertTo converts this Organization to the Hub version (v1beta1)
+func (src *Organization) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.Organization)
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+
+	// Restore the fields that cannot be represented by v1alpha1, the
+	// ones defined by v1alpha1 are then set on top of them
+	if err := unmarshalConversionData(src, dst, &dst.Spec, &dst.Status); err != nil {
+		return err
+	}
+
+	dst.Spec.AdminGroups = src.Spec.AdminGroups
+	dst.Spec.EditorGroups = src.Spec.EditorGroups
+	dst.Spec.ViewerGroups = src.Spec.ViewerGroups
+	dst.Spec.DefaultNamespaceLabels = src.Spec.DefaultNamespaceLabels
+
+	dst.Status.SpacesNamespace = src.Status.SpacesNamespace
+	dst.Status.SpaceCount = src.Status.SpaceCount
+	dst.Status.Spaces = nil
+	for _, space := range src.Status.Spaces {
+		dst.Status.Spaces = append(dst.Status.Spaces, v1beta1.SpaceSummary{
+			Name:  space.Name,
+			Phase: v1beta1.SpacePhase(space.Phase),
+		})
+	}
+	dst.Status.Phase = v1beta1.OrganizationPhase(src.Status.Phase)
+	dst.Status.Conditions = convertConditionsTo(src.Status.Conditions)
+	dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version
+func (dst *Organization) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.Organization)
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+
+	dst.Spec.AdminGroups = src.Spec.AdminGroups
+	dst.Spec.EditorGroups = src.Spec.EditorGroups
+	dst.Spec.ViewerGroups = src.Spec.ViewerGroups
+	dst.Spec.DefaultNamespaceLabels = src.Spec.DefaultNamespaceLabels
+
+	dst.Status.SpacesNamespace = src.Status.SpacesNamespace
+	dst.Status.SpaceCount = src.Status.SpaceCount
+	dst.Status.Spaces = nil
+	for _, space := range src.Status.Spaces {
+		dst.Status.Spaces = append(dst.Status.Spaces, SpaceSummary{
+			Name:  space.Name,
+			Phase: SpacePhase(space.Phase),
+		})
+	}
+	dst.Status.Phase = OrganizationPhase(src.Status.Phase)
+	dst.Status.Conditions = convertConditionsFrom(src.Status.Conditions)
+	dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+
+	// Preserve the whole hub object, this makes the round trip lossless
+	return marshalConversionData(dst, src.Spec, src.Status)
+}
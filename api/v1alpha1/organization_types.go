@@ -43,15 +43,68 @@ type OrganizationSpec struct {
 	DefaultNamespaceLabels map[string]string `json:"default_namespace_labels"`
 }
 
+// OrganizationPhase is a label for the condition of an Organization at the
+// current time
+type OrganizationPhase string
+
+const (
+	// OrganizationPhasePending means the resources of the Organization are
+	// not ready yet
+	OrganizationPhasePending OrganizationPhase = "Pending"
+	// OrganizationPhaseReady means all the resources of the Organization
+	// are in place
+	OrganizationPhaseReady OrganizationPhase = "Ready"
+	// OrganizationPhaseDegraded means either the last reconciliation of the
+	// Organization failed or some of its Spaces are in error
+	OrganizationPhaseDegraded OrganizationPhase = "Degraded"
+	// OrganizationPhaseTerminating means the Organization is being deleted
+	OrganizationPhaseTerminating OrganizationPhase = "Terminating"
+)
+
+// SpaceSummary reports the state of one of the Spaces of an Organization
+type SpaceSummary struct {
+	// Name of the Space
+	Name string `json:"name"`
+
+	// Current phase of the Space
+	// +optional
+	Phase SpacePhase `json:"phase,omitempty"`
+}
+
 // OrganizationStatus defines the observed state of Organization
 type OrganizationStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Name of the Namespace holding the Space objects of the Organization
+	// +optional
+	SpacesNamespace string `json:"spacesNamespace,omitempty"`
+
+	// Number of Spaces owned by the Organization
+	// +optional
+	SpaceCount int32 `json:"spaceCount"`
+
+	// Name and phase of each Space owned by the Organization
+	// +optional
+	Spaces []SpaceSummary `json:"spaces,omitempty"`
+
+	// Current phase of the Organization
+	// +optional
+	Phase OrganizationPhase `json:"phase,omitempty"`
+
+	// Latest available observations of the state of the Organization
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// The generation observed by the Organization controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=organizations,scope=Cluster
+// +kubebuilder:printcolumn:name="Spaces Namespace",type="string",JSONPath=".status.spacesNamespace"
+// +kubebuilder:printcolumn:name="Spaces",type="integer",JSONPath=".status.spaceCount"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Organization is the Schema for the organizations API
 type Organization struct {
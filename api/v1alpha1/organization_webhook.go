@@ -0,0 +1,96 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/flavio/organization-operator/api/v1beta1"
+)
+
+// log is for logging in this package.
+var organizationlog = logf.Log.WithName("organization-resource")
+
+func (r *Organization) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-k8s-suse-com-v1alpha1-organization,mutating=true,failurePolicy=fail,groups=k8s.suse.com,resources=organizations,verbs=create;update,versions=v1alpha1,name=morganization.kb.io
+
+var _ webhook.Defaulter = &Organization{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+// Mutation webhook for Organization objects. The defaults are set by the
+// storage version of the object, the fields v1alpha1 cannot represent are
+// kept inside of the conversion data.
+func (r *Organization) Default() {
+	hub := &v1beta1.Organization{}
+	if err := r.ConvertTo(hub); err != nil {
+		// The validating webhook rejects the object
+		organizationlog.Error(err, "Cannot convert Organization to the storage version")
+		return
+	}
+	hub.Default()
+	if err := r.ConvertFrom(hub); err != nil {
+		organizationlog.Error(err, "Cannot convert Organization from the storage version")
+	}
+}
+
+// +kubebuilder:webhook:verbs=create;update;delete,path=/validate-k8s-suse-com-v1alpha1-organization,mutating=false,failurePolicy=fail,groups=k8s.suse.com,resources=organizations,versions=v1alpha1,name=vorganization.kb.io
+
+var _ webhook.Validator = &Organization{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+// The validation is performed by the storage version of the object.
+func (r *Organization) ValidateCreate() error {
+	hub := &v1beta1.Organization{}
+	if err := r.ConvertTo(hub); err != nil {
+		return err
+	}
+	return hub.ValidateCreate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+// The validation is performed by the storage version of the object.
+func (r *Organization) ValidateUpdate(old runtime.Object) error {
+	hub := &v1beta1.Organization{}
+	if err := r.ConvertTo(hub); err != nil {
+		return err
+	}
+	oldHub := &v1beta1.Organization{}
+	if oldOrganization, ok := old.(*Organization); ok {
+		if err := oldOrganization.ConvertTo(oldHub); err != nil {
+			return err
+		}
+	}
+	return hub.ValidateUpdate(oldHub)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
+// The validation is performed by the storage version of the object.
+func (r *Organization) ValidateDelete() error {
+	hub := &v1beta1.Organization{}
+	if err := r.ConvertTo(hub); err != nil {
+		return err
+	}
+	return hub.ValidateDelete()
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/flavio/organization-operator/api/v1beta1"
+)
+
+var _ conversion.Convertible = &Space{}
+
+// ConvertTo converts this Space to the Hub version (v1beta1)
+func (src *Space) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.Space)
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+
+	// Restore the fields that cannot be represented by v1alpha1, the
+	// ones defined by v1alpha1 are then set on top of them
+	if err := unmarshalConversionData(src, dst, &dst.Spec, &dst.Status); err != nil {
+		return err
+	}
+
+	dst.Spec.AdminGroups = src.Spec.AdminGroups
+	dst.Spec.EditorGroups = src.Spec.EditorGroups
+	dst.Spec.ViewerGroups = src.Spec.ViewerGroups
+	dst.Spec.Admins = src.Spec.Admins
+	dst.Spec.Editors = src.Spec.Editors
+	dst.Spec.Viewers = src.Spec.Viewers
+
+	dst.Status.Namespace = src.Status.Namespace
+	dst.Status.Phase = v1beta1.SpacePhase(src.Status.Phase)
+	dst.Status.Conditions = convertConditionsTo(src.Status.Conditions)
+	dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+	dst.Status.LastReconcileError = src.Status.LastReconcileError
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version
+func (dst *Space) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.Space)
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+
+	dst.Spec.AdminGroups = src.Spec.AdminGroups
+	dst.Spec.EditorGroups = src.Spec.EditorGroups
+	dst.Spec.ViewerGroups = src.Spec.ViewerGroups
+	dst.Spec.Admins = src.Spec.Admins
+	dst.Spec.Editors = src.Spec.Editors
+	dst.Spec.Viewers = src.Spec.Viewers
+
+	dst.Status.Namespace = src.Status.Namespace
+	dst.Status.Phase = SpacePhase(src.Status.Phase)
+	dst.Status.Conditions = convertConditionsFrom(src.Status.Conditions)
+	dst.Status.ObservedGeneration = src.Status.ObservedGeneration
+	dst.Status.LastReconcileError = src.Status.LastReconcileError
+
+	// Preserve the whole hub object, this makes the round trip lossless
+	return marshalConversionData(dst, src.Spec, src.Status)
+}
@@ -50,14 +50,49 @@ type SpaceSpec struct {
 	Viewers []string `json:"viewers"`
 }
 
+// SpacePhase is a label for the condition of a Space at the current time
+type SpacePhase string
+
+const (
+	// SpacePhasePending means the resources of the Space are not ready yet
+	SpacePhasePending SpacePhase = "Pending"
+	// SpacePhaseReady means all the resources of the Space are in place
+	SpacePhaseReady SpacePhase = "Ready"
+	// SpacePhaseTerminating means the Space is being deleted
+	SpacePhaseTerminating SpacePhase = "Terminating"
+	// SpacePhaseError means the last reconciliation of the Space failed
+	SpacePhaseError SpacePhase = "Error"
+)
+
 // SpaceStatus defines the observed state of Space
 type SpaceStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Name of the Namespace managed by the Space
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Current phase of the Space
+	// +optional
+	Phase SpacePhase `json:"phase,omitempty"`
+
+	// Latest available observations of the state of the Space
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// The generation observed by the Space controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Error that caused the last reconciliation to fail, empty when the
+	// last reconciliation was successful
+	// +optional
+	LastReconcileError string `json:"lastReconcileError,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Namespace",type="string",JSONPath=".status.namespace"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Space is the Schema for the spaces API
 type Space struct {
@@ -21,13 +21,20 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
-	"github.com/flavio/organization-operator/pkg/common"
+	"github.com/flavio/organization-operator/api/v1beta1"
 )
 
 // log is for logging in this package.
 var spacelog = logf.Log.WithName("space-resource")
 
+// +kubebuilder:webhook:verbs=create;update,path=/validate-k8s-suse-com-v1alpha1-space,mutating=false,failurePolicy=fail,groups=k8s.suse.com,resources=spaces,versions=v1alpha1,name=vspace.kb.io
+
+// SetupWebhookWithManager registers the webhooks of Space. The validation is
+// performed by the storage version of the object, which needs the user making
+// the request.
 func (r *Space) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register("/validate-k8s-suse-com-v1alpha1-space",
+		&webhook.Admission{Handler: v1beta1.NewSpaceValidatingHandler(r)})
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		Complete()
@@ -40,22 +47,22 @@ func (r *Space) SetupWebhookWithManager(mgr ctrl.Manager) error {
 var _ webhook.Defaulter = &Space{}
 
 // Default implements webhook.Defaulter so a webhook will be registered for the type
-// Mutation webhook for Space objects. It ensures a proper finalizer is set.
+// Mutation webhook for Space objects. The defaults are set by the storage
+// version of the object, the fields v1alpha1 cannot represent are kept
+// inside of the conversion data.
 func (r *Space) Default() {
 	spacelog.Info("Setting default values for Space object",
 		"Namespace", r.Namespace,
 		"Name", r.Name)
-	spaceFinalizerFound := false
 
-	finalizers := r.ObjectMeta.GetFinalizers()
-	for _, finalizer := range finalizers {
-		if finalizer == common.SpaceFinalizer {
-			spaceFinalizerFound = true
-		}
+	hub := &v1beta1.Space{}
+	if err := r.ConvertTo(hub); err != nil {
+		// The validating webhook rejects the object
+		spacelog.Error(err, "Cannot convert Space to the storage version")
+		return
 	}
-
-	if !spaceFinalizerFound {
-		finalizers = append(finalizers, common.SpaceFinalizer)
-		r.SetFinalizers(finalizers)
+	hub.Default()
+	if err := r.ConvertFrom(hub); err != nil {
+		spacelog.Error(err, "Cannot convert Space from the storage version")
 	}
 }
@@ -0,0 +1,145 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// requestValidator is implemented by the objects whose validation depends on
+// the user making the request. The generic webhook.Validator doesn't give
+// access to the admission request.
+type requestValidator interface {
+	runtime.Object
+
+	// validateRequest checks the object can be written by the given user,
+	// old is nil when the object is being created
+	validateRequest(ctx context.Context, user authenticationv1.UserInfo, old runtime.Object) error
+}
+
+// requestValidatingHandler is the admission handler of the objects
+// implementing requestValidator
+type requestValidatingHandler struct {
+	object requestValidator
+	// spoke is set when the admission requests hold another version of
+	// object, they are converted to object before being validated
+	spoke   conversion.Convertible
+	decoder *admission.Decoder
+}
+
+// NewSpaceValidatingHandler returns the admission handler validating the
+// given version of Space. The validation is performed by the storage
+// version of the object.
+func NewSpaceValidatingHandler(spoke conversion.Convertible) admission.Handler {
+	return &requestValidatingHandler{object: &Space{}, spoke: spoke}
+}
+
+var _ admission.DecoderInjector = &requestValidatingHandler{}
+
+// InjectDecoder injects the decoder of the admission requests
+func (h *requestValidatingHandler) InjectDecoder(decoder *admission.Decoder) error {
+	h.decoder = decoder
+	return nil
+}
+
+// Handle validates the object of the admission request
+func (h *requestValidatingHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var obj, old requestValidator
+	var err error
+
+	switch req.Operation {
+	case admissionv1beta1.Create:
+		if obj, err = h.decode(req.Object); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	case admissionv1beta1.Update:
+		if obj, err = h.decode(req.Object); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if old, err = h.decode(req.OldObject); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	default:
+		return admission.Allowed("")
+	}
+
+	// old must be a nil interface when the object is being created
+	var oldObject runtime.Object
+	if old != nil {
+		oldObject = old
+	}
+	if err := obj.validateRequest(ctx, req.UserInfo, oldObject); err != nil {
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+// decode returns the object held by raw, converted to the type of h.object
+// when the handler serves another version of it
+func (h *requestValidatingHandler) decode(raw runtime.RawExtension) (requestValidator, error) {
+	obj := h.object.DeepCopyObject().(requestValidator)
+	if h.spoke == nil {
+		return obj, h.decoder.DecodeRaw(raw, obj)
+	}
+
+	spoke := h.spoke.DeepCopyObject().(conversion.Convertible)
+	if err := h.decoder.DecodeRaw(raw, spoke); err != nil {
+		return nil, err
+	}
+	hub, ok := obj.(conversion.Hub)
+	if !ok {
+		return nil, fmt.Errorf("%T is not the storage version of %T", obj, spoke)
+	}
+	if err := spoke.ConvertTo(hub); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// userCan returns true when the user is allowed to perform the action
+// described by the given attributes
+func userCan(ctx context.Context, user authenticationv1.UserInfo, attributes authorizationv1.ResourceAttributes) (bool, error) {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &attributes,
+			User:               user.Username,
+			UID:                user.UID,
+			Groups:             user.Groups,
+			Extra:              extra,
+		},
+	}
+	if err := webhookClient.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
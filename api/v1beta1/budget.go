@@ -0,0 +1,74 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// AllocatedBudget returns, for each resource of the budget, the sum of the
+// hard limits enforced inside of the Namespaces of the given Spaces. The
+// quota of each Space is merged with the one of the SpaceExtraConfig objects
+// applying to it, like it's done by the ResourceQuota of the Space.
+func AllocatedBudget(budget corev1.ResourceList, spaces []Space, extraConfigs []SpaceExtraConfig) corev1.ResourceList {
+	allocated := corev1.ResourceList{}
+	for name := range budget {
+		allocated[name] = resource.Quantity{}
+	}
+	for i := range spaces {
+		hard := hardLimitsOfSpace(&spaces[i], extraConfigs)
+		for name := range budget {
+			if quantity, found := hard[name]; found {
+				total := allocated[name]
+				total.Add(quantity)
+				allocated[name] = total
+			}
+		}
+	}
+	return allocated
+}
+
+// hardLimitsOfSpace returns the hard limits of the ResourceQuota enforced
+// inside of the Namespace of the Space: the ones of the Space overridden by
+// the ones of the SpaceExtraConfig objects applying to it
+func hardLimitsOfSpace(space *Space, extraConfigs []SpaceExtraConfig) corev1.ResourceList {
+	var hard corev1.ResourceList
+	if space.Spec.Quota != nil {
+		hard = space.Spec.Quota.Hard
+	}
+	extraConfig := MergeSpaceExtraConfigs(extraConfigs, space.Name)
+	if extraConfig.ResourceQuota != nil {
+		hard = mergeResourceLists(hard, extraConfig.ResourceQuota.Hard)
+	}
+	return hard
+}
+
+// AvailableBudget returns, for each resource of the budget, the amount that
+// is not allocated yet. The amount is negative when the budget has been
+// overcommitted.
+func AvailableBudget(budget, allocated corev1.ResourceList) corev1.ResourceList {
+	available := corev1.ResourceList{}
+	for name, quantity := range budget {
+		left := quantity.DeepCopy()
+		if used, found := allocated[name]; found {
+			left.Sub(used)
+		}
+		available[name] = left
+	}
+	return available
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+func cpu(quantity string) corev1.ResourceList {
+	return corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse(quantity)}
+}
+
+func spaceWithQuota(name string, hard corev1.ResourceList) Space {
+	space := Space{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "acme-spaces"}}
+	if hard != nil {
+		space.Spec.Quota = &SpaceQuota{Hard: hard}
+	}
+	return space
+}
+
+func quotaConfig(name string, spaces []string, hard corev1.ResourceList) SpaceExtraConfig {
+	return extraConfig(name, spaces, SpaceExtraConfigSpec{
+		ResourceQuota: &corev1.ResourceQuotaSpec{Hard: hard},
+	})
+}
+
+func TestAllocatedBudget(t *testing.T) {
+	budget := corev1.ResourceList{
+		corev1.ResourceRequestsCPU:    resource.MustParse("10"),
+		corev1.ResourceRequestsMemory: resource.MustParse("10Gi"),
+	}
+
+	tests := []struct {
+		name         string
+		spaces       []Space
+		extraConfigs []SpaceExtraConfig
+		expected     corev1.ResourceList
+	}{
+		{
+			name:   "no Spaces",
+			spaces: nil,
+			expected: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("0"),
+				corev1.ResourceRequestsMemory: resource.MustParse("0"),
+			},
+		},
+		{
+			name: "quotas of the Spaces are summed",
+			spaces: []Space{
+				spaceWithQuota("web", corev1.ResourceList{
+					corev1.ResourceRequestsCPU: resource.MustParse("1500m"),
+					corev1.ResourcePods:        resource.MustParse("10"),
+				}),
+				spaceWithQuota("api", cpu("2")),
+				spaceWithQuota("docs", nil),
+			},
+			expected: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("3500m"),
+				corev1.ResourceRequestsMemory: resource.MustParse("0"),
+			},
+		},
+		{
+			name: "SpaceExtraConfig overrides the quota of a Space",
+			spaces: []Space{
+				spaceWithQuota("web", cpu("1")),
+				spaceWithQuota("api", cpu("2")),
+			},
+			extraConfigs: []SpaceExtraConfig{quotaConfig("big-web", []string{"web"}, cpu("4"))},
+			expected: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("6"),
+				corev1.ResourceRequestsMemory: resource.MustParse("0"),
+			},
+		},
+		{
+			name: "SpaceExtraConfig sets the quota of all the Spaces",
+			spaces: []Space{
+				spaceWithQuota("web", cpu("1")),
+				spaceWithQuota("docs", nil),
+			},
+			extraConfigs: []SpaceExtraConfig{
+				quotaConfig("memory", nil, corev1.ResourceList{corev1.ResourceRequestsMemory: resource.MustParse("1Gi")}),
+			},
+			expected: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("1"),
+				corev1.ResourceRequestsMemory: resource.MustParse("2Gi"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allocated := AllocatedBudget(budget, test.spaces, test.extraConfigs)
+			if !equality.Semantic.DeepEqual(allocated, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, allocated)
+			}
+		})
+	}
+}
+
+func TestAvailableBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		budget    corev1.ResourceList
+		allocated corev1.ResourceList
+		expected  corev1.ResourceList
+	}{
+		{"nothing allocated", cpu("4"), corev1.ResourceList{}, cpu("4")},
+		{"partially allocated", cpu("4"), cpu("1500m"), cpu("2500m")},
+		{"overcommitted", cpu("4"), cpu("5"), cpu("-1")},
+		{"allocation outside of the budget is ignored", cpu("4"), corev1.ResourceList{
+			corev1.ResourcePods: resource.MustParse("10"),
+		}, cpu("4")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			available := AvailableBudget(test.budget, test.allocated)
+			if !equality.Semantic.DeepEqual(available, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, available)
+			}
+		})
+	}
+}
+
+// organizationObjects returns the Organization acme, with the given budget,
+// and the Namespace holding its Spaces
+func organizationObjects(budget corev1.ResourceList) []runtime.Object {
+	controller := true
+	return []runtime.Object{
+		&Organization{
+			ObjectMeta: metav1.ObjectMeta{Name: "acme"},
+			Spec:       OrganizationSpec{Budget: budget},
+		},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "acme-spaces",
+			Labels: map[string]string{common.SpacesNamespaceLabel: "acme"},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: GroupVersion.String(),
+				Kind:       "Organization",
+				Name:       "acme",
+				Controller: &controller,
+			}},
+		}},
+	}
+}
+
+func TestSpaceBudgetCountsSpaceExtraConfigs(t *testing.T) {
+	organization := &Organization{
+		ObjectMeta: metav1.ObjectMeta{Name: "acme"},
+		Spec:       OrganizationSpec{Budget: cpu("4")},
+	}
+	api := spaceWithQuota("api", cpu("1"))
+	bigWeb := quotaConfig("big-web", []string{"web"}, cpu("3"))
+	defer useWebhookClient(&api, &bigWeb)()
+
+	tests := []struct {
+		name     string
+		quota    corev1.ResourceList
+		errField string
+	}{
+		// The SpaceExtraConfig wins over the quota of the Space: 1 + 3
+		{"within the budget", cpu("1"), ""},
+		{"SpaceExtraConfig wins even when the Space asks for less", cpu("100m"), ""},
+		{"no quota, the SpaceExtraConfig provides it", nil, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			web := spaceWithQuota("web", test.quota)
+			allErrs, err := web.validateBudget(organization)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertFieldError(t, allErrs, test.errField)
+		})
+	}
+
+	// Without room left for the SpaceExtraConfig the Space is rejected
+	organization.Spec.Budget = cpu("3")
+	web := spaceWithQuota("web", cpu("1"))
+	allErrs, err := web.validateBudget(organization)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertFieldError(t, allErrs, "spec.quota.hard[requests.cpu]")
+}
+
+func TestSpaceExtraConfigBudget(t *testing.T) {
+	web := spaceWithQuota("web", cpu("1"))
+	api := spaceWithQuota("api", cpu("1"))
+	existing := quotaConfig("big-api", []string{"api"}, cpu("2"))
+
+	tests := []struct {
+		name     string
+		budget   corev1.ResourceList
+		config   SpaceExtraConfig
+		errField string
+	}{
+		{
+			name:   "within the budget",
+			budget: cpu("5"),
+			config: quotaConfig("big-web", []string{"web"}, cpu("2")),
+		},
+		{
+			name:     "over the budget",
+			budget:   cpu("3500m"),
+			config:   quotaConfig("big-web", []string{"web"}, cpu("2")),
+			errField: "spec.resourceQuota.hard[requests.cpu]",
+		},
+		{
+			// big-api still wins for the Space api: 3 + 2
+			name:     "applied to all the Spaces",
+			budget:   cpu("4"),
+			config:   quotaConfig("all", nil, cpu("3")),
+			errField: "spec.resourceQuota.hard[requests.cpu]",
+		},
+		{
+			name:   "update lowering the quota",
+			budget: cpu("2"),
+			config: quotaConfig("big-api", []string{"api"}, cpu("1500m")),
+		},
+		{
+			name:   "resources outside of the budget",
+			budget: corev1.ResourceList{corev1.ResourceRequestsMemory: resource.MustParse("1Gi")},
+			config: quotaConfig("big-web", []string{"web"}, cpu("10")),
+		},
+		{
+			name:   "no budget",
+			budget: nil,
+			config: quotaConfig("big-web", []string{"web"}, cpu("10")),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objects := append(organizationObjects(test.budget), &web, &api, &existing)
+			defer useWebhookClient(objects...)()
+
+			allErrs, err := test.config.validateBudget(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertFieldError(t, allErrs, test.errField)
+		})
+	}
+}
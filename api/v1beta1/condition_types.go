@@ -0,0 +1,93 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition describes one aspect of the current state of a resource.
+// It follows the same conventions of the conditions used by the
+// kubernetes core resources.
+type Condition struct {
+	// Type of the condition, in CamelCase
+	Type string `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// The .metadata.generation the condition was set upon
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Last time the condition transitioned from one status to another
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// Programmatic identifier, in CamelCase, of the reason for the
+	// condition's last transition
+	Reason string `json:"reason"`
+
+	// Human readable message with details about the transition
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// SetCondition adds the given condition to the list. If a condition with the
+// same type is already present it is updated; its LastTransitionTime is
+// changed only when the status changes.
+func SetCondition(conditions *[]Condition, newCondition Condition) {
+	existing := FindCondition(*conditions, newCondition.Type)
+	if existing == nil {
+		if newCondition.LastTransitionTime.IsZero() {
+			newCondition.LastTransitionTime = metav1.Now()
+		}
+		*conditions = append(*conditions, newCondition)
+		return
+	}
+
+	if existing.Status != newCondition.Status {
+		existing.Status = newCondition.Status
+		if newCondition.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = metav1.Now()
+		} else {
+			existing.LastTransitionTime = newCondition.LastTransitionTime
+		}
+	}
+
+	existing.Reason = newCondition.Reason
+	existing.Message = newCondition.Message
+	existing.ObservedGeneration = newCondition.ObservedGeneration
+}
+
+// FindCondition returns the condition with the given type, nil when it
+// cannot be found.
+func FindCondition(conditions []Condition, conditionType string) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsConditionTrue returns true when the condition with the given type is
+// present and its status is True.
+func IsConditionTrue(conditions []Condition, conditionType string) bool {
+	condition := FindCondition(conditions, conditionType)
+	return condition != nil && condition.Status == corev1.ConditionTrue
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OrganizationSpec defines the desired state of Organization
+type OrganizationSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// Optional names of groups with admin rights
+	// +optional
+	AdminGroups []string `json:"adminGroups,omitempty"`
+
+	// Optional names of groups with edit rights
+	// +optional
+	EditorGroups []string `json:"editorGroups,omitempty"`
+
+	// optional names of groups with view rights
+	// +optional
+	ViewerGroups []string `json:"viewerGroups,omitempty"`
+
+	// Optional names of users with admin rights
+	// +optional
+	Admins []string `json:"admins,omitempty"`
+
+	// Optional names of users with edit rights
+	// +optional
+	Editors []string `json:"editors,omitempty"`
+
+	// Optional names of users with view rights
+	// +optional
+	Viewers []string `json:"viewers,omitempty"`
+
+	// Optional ServiceAccounts with admin rights, in the "namespace/name"
+	// format
+	// +optional
+	AdminServiceAccounts []string `json:"adminServiceAccounts,omitempty"`
+
+	// Optional ServiceAccounts with edit rights, in the "namespace/name"
+	// format
+	// +optional
+	EditorServiceAccounts []string `json:"editorServiceAccounts,omitempty"`
+
+	// Optional ServiceAccounts with view rights, in the "namespace/name"
+	// format
+	// +optional
+	ViewerServiceAccounts []string `json:"viewerServiceAccounts,omitempty"`
+
+	// optional map with all the labels to add to Namespaces owned by the
+	// organization
+	// +optional
+	DefaultNamespaceLabels map[string]string `json:"defaultNamespaceLabels,omitempty"`
+
+	// optional map with all the annotations to add to the Namespaces owned
+	// by the organization
+	// +optional
+	DefaultNamespaceAnnotations map[string]string `json:"defaultNamespaceAnnotations,omitempty"`
+
+	// Optional defaults and constraints for the compute resources of the
+	// containers running inside of the Namespaces of the Spaces. Each Space
+	// can override them.
+	// +optional
+	DefaultContainerLimits *ContainerLimits `json:"defaultContainerLimits,omitempty"`
+
+	// Optional names of the ClusterRoles granted to the admins, editors
+	// and viewers of the Spaces. They override the ones configured for
+	// the operator.
+	// +optional
+	ClusterRoles *ClusterRoleMapping `json:"clusterRoles,omitempty"`
+
+	// Optional network isolation of the Namespaces of the Spaces, each
+	// Space can override it. Defaults to "none".
+	// +optional
+	NetworkIsolation NetworkIsolation `json:"networkIsolation,omitempty"`
+
+	// What happens to the Spaces when the Organization is deleted.
+	// Cascade deletes all the Spaces, together with their Namespaces,
+	// before the Organization. Block prevents the deletion of the
+	// Organization while it has Spaces. Defaults to Cascade.
+	// +optional
+	DeletionPolicy OrganizationDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Optional resources the Organization can distribute among its Spaces.
+	// The sum of the hard limits set by the quotas of the Spaces cannot
+	// exceed it; Spaces must set a quota for each resource of the budget.
+	// +optional
+	Budget corev1.ResourceList `json:"budget,omitempty"`
+}
+
+// ClusterRoleMapping maps each tier of users of a Space to the name of the
+// ClusterRole granting its rights
+type ClusterRoleMapping struct {
+	// ClusterRole of the admins
+	// +optional
+	Admin string `json:"admin,omitempty"`
+
+	// ClusterRole of the editors
+	// +optional
+	Edit string `json:"edit,omitempty"`
+
+	// ClusterRole of the viewers
+	// +optional
+	View string `json:"view,omitempty"`
+}
+
+// Override returns a new mapping where the ClusterRoles set by override take
+// precedence over the ones of the receiver
+func (m ClusterRoleMapping) Override(override *ClusterRoleMapping) ClusterRoleMapping {
+	if override == nil {
+		return m
+	}
+	if override.Admin != "" {
+		m.Admin = override.Admin
+	}
+	if override.Edit != "" {
+		m.Edit = override.Edit
+	}
+	if override.View != "" {
+		m.View = override.View
+	}
+	return m
+}
+
+// OrganizationDeletionPolicy defines what happens to the Spaces of an
+// Organization when the Organization is deleted
+// +kubebuilder:validation:Enum=Cascade;Block
+type OrganizationDeletionPolicy string
+
+const (
+	// OrganizationDeletionPolicyCascade deletes all the Spaces before
+	// deleting the Organization
+	OrganizationDeletionPolicyCascade OrganizationDeletionPolicy = "Cascade"
+	// OrganizationDeletionPolicyBlock prevents the deletion of an
+	// Organization that still has Spaces
+	OrganizationDeletionPolicyBlock OrganizationDeletionPolicy = "Block"
+)
+
+// OrganizationPhase is a label for the condition of an Organization at the
+// current time
+type OrganizationPhase string
+
+const (
+	// OrganizationPhasePending means the resources of the Organization are
+	// not ready yet
+	OrganizationPhasePending OrganizationPhase = "Pending"
+	// OrganizationPhaseReady means all the resources of the Organization
+	// are in place
+	OrganizationPhaseReady OrganizationPhase = "Ready"
+	// OrganizationPhaseDegraded means either the last reconciliation of the
+	// Organization failed, some of its Spaces are in error or its Spaces
+	// exceed its budget
+	OrganizationPhaseDegraded OrganizationPhase = "Degraded"
+	// OrganizationPhaseTerminating means the Organization is being deleted
+	OrganizationPhaseTerminating OrganizationPhase = "Terminating"
+)
+
+const (
+	// OrganizationConditionReady is True when the Namespace holding the
+	// Spaces and all the scope Roles and RoleBindings are in place
+	OrganizationConditionReady = "Ready"
+	// OrganizationConditionDegraded is True when the last reconciliation
+	// failed, some of the Spaces are in error or the Spaces exceed the
+	// budget
+	OrganizationConditionDegraded = "Degraded"
+	// OrganizationConditionRBACSynced is True when the scope Roles and
+	// RoleBindings match the desired state
+	OrganizationConditionRBACSynced = "RBACSynced"
+	// OrganizationConditionBudgetExceeded is True when the resources
+	// allocated to the Spaces exceed the budget of the Organization
+	OrganizationConditionBudgetExceeded = "BudgetExceeded"
+)
+
+// SpaceSummary reports the state of one of the Spaces of an Organization
+type SpaceSummary struct {
+	// Name of the Space
+	Name string `json:"name"`
+
+	// Current phase of the Space
+	// +optional
+	Phase SpacePhase `json:"phase,omitempty"`
+}
+
+// OrganizationStatus defines the observed state of Organization
+type OrganizationStatus struct {
+	// Name of the Namespace holding the Space objects of the Organization
+	// +optional
+	SpacesNamespace string `json:"spacesNamespace,omitempty"`
+
+	// Number of Spaces owned by the Organization
+	// +optional
+	SpaceCount int32 `json:"spaceCount"`
+
+	// Name and phase of each Space owned by the Organization
+	// +optional
+	Spaces []SpaceSummary `json:"spaces,omitempty"`
+
+	// Current phase of the Organization
+	// +optional
+	Phase OrganizationPhase `json:"phase,omitempty"`
+
+	// Latest available observations of the state of the Organization
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// The generation observed by the Organization controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Part of the budget allocated to the Spaces through their quotas
+	// +optional
+	AllocatedBudget corev1.ResourceList `json:"allocatedBudget,omitempty"`
+
+	// Part of the budget that can still be allocated to Spaces
+	// +optional
+	AvailableBudget corev1.ResourceList `json:"availableBudget,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=organizations,scope=Cluster
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Spaces Namespace",type="string",JSONPath=".status.spacesNamespace"
+// +kubebuilder:printcolumn:name="Spaces",type="integer",JSONPath=".status.spaceCount"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Organization is the Schema for the organizations API
+type Organization struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OrganizationSpec   `json:"spec,omitempty"`
+	Status OrganizationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OrganizationList contains a list of Organization
+type OrganizationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Organization `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Organization{}, &OrganizationList{})
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// log is for logging in this package.
+var organizationlog = logf.Log.WithName("organization-resource")
+
+func (r *Organization) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookClient = mgr.GetClient()
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-k8s-suse-com-v1beta1-organization,mutating=true,failurePolicy=fail,groups=k8s.suse.com,resources=organizations,verbs=create;update,versions=v1beta1,name=morganization.v1beta1.kb.io
+
+var _ webhook.Defaulter = &Organization{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+// Mutation webhook for Organization objects. It ensures a proper finalizer is set.
+func (r *Organization) Default() {
+	organizationlog.Info("Setting default values for Organization object",
+		"Name", r.Name)
+	if r.GetDeletionTimestamp() != nil {
+		return
+	}
+
+	if r.Spec.DeletionPolicy == "" {
+		r.Spec.DeletionPolicy = OrganizationDeletionPolicyCascade
+	}
+
+	for _, finalizer := range r.GetFinalizers() {
+		if finalizer == common.OrganizationFinalizer {
+			return
+		}
+	}
+	r.SetFinalizers(append(r.GetFinalizers(), common.OrganizationFinalizer))
+}
+
+// +kubebuilder:webhook:verbs=create;update;delete,path=/validate-k8s-suse-com-v1beta1-organization,mutating=false,failurePolicy=fail,groups=k8s.suse.com,resources=organizations,versions=v1beta1,name=vorganization.v1beta1.kb.io
+
+var _ webhook.Validator = &Organization{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Organization) ValidateCreate() error {
+	organizationlog.Info("Validating creation of Organization object",
+		"Name", r.Name)
+	return r.validate(nil)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Organization) ValidateUpdate(old runtime.Object) error {
+	organizationlog.Info("Validating update of Organization object",
+		"Name", r.Name)
+	if r.GetDeletionTimestamp() != nil {
+		// Never prevent the removal of the finalizers
+		return nil
+	}
+	oldOrganization, _ := old.(*Organization)
+	return r.validate(oldOrganization)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Organization) ValidateDelete() error {
+	organizationlog.Info("Validating deletion of Organization object",
+		"Name", r.Name)
+	if r.Spec.DeletionPolicy != OrganizationDeletionPolicyBlock || webhookClient == nil {
+		return nil
+	}
+
+	spaces := &SpaceList{}
+	err := webhookClient.List(context.Background(), spaces,
+		client.InNamespace(common.ComputeSpacesNamespaceFromOrganizationName(r.Name)))
+	if err != nil {
+		return err
+	}
+	if len(spaces.Items) == 0 {
+		return nil
+	}
+	return apierrors.NewForbidden(
+		GroupVersion.WithResource("organizations").GroupResource(), r.Name,
+		fmt.Errorf("the deletion policy is %s and the Organization still has %d Spaces",
+			OrganizationDeletionPolicyBlock, len(spaces.Items)))
+}
+
+// validate runs all the checks against the Organization, old is nil when the
+// Organization is being created
+func (r *Organization) validate(old *Organization) error {
+	allErrs := r.validateMembers()
+	allErrs = append(allErrs, r.validateNamespaceMetadata()...)
+	if old == nil {
+		nameErrs, err := r.validateName()
+		if err != nil {
+			return err
+		}
+		allErrs = append(allErrs, nameErrs...)
+	}
+
+	checkAllocation := old != nil && !equality.Semantic.DeepEqual(old.Spec.Budget, r.Spec.Budget)
+	budgetErrs, err := r.validateBudget(checkAllocation)
+	if err != nil {
+		return err
+	}
+	allErrs = append(allErrs, budgetErrs...)
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Organization").GroupKind(), r.Name, allErrs)
+}
+
+// validateName checks the name of the Organization is not reserved and can
+// be used to build the names of the Namespaces of the Organization
+func (r *Organization) validateName() (field.ErrorList, error) {
+	allErrs := field.ErrorList{}
+	namePath := field.NewPath("metadata", "name")
+
+	if r.Name == "kube" || r.Name == "default" || strings.HasPrefix(r.Name, "kube-") {
+		allErrs = append(allErrs, field.Forbidden(namePath,
+			fmt.Sprintf("%s is reserved", r.Name)))
+		return allErrs, nil
+	}
+	// The names of the Namespaces are shortened when needed, the labels
+	// holding the name of the Organization are not
+	allErrs = append(allErrs, validateLabelValueName(r.Name, namePath)...)
+	if len(allErrs) > 0 {
+		return allErrs, nil
+	}
+
+	spacesNamespace := common.ComputeSpacesNamespaceFromOrganizationName(r.Name)
+	for _, msg := range validation.IsDNS1123Label(spacesNamespace) {
+		allErrs = append(allErrs, field.Invalid(namePath, r.Name,
+			fmt.Sprintf("cannot be used to create the Namespace %s: %s", spacesNamespace, msg)))
+	}
+	// Leave room for the name of at least one Space
+	spaceNamespace := common.NameOfNamespaceCreateBySpace(r.Name, "x")
+	for _, msg := range validation.IsDNS1123Label(spaceNamespace) {
+		allErrs = append(allErrs, field.Invalid(namePath, r.Name,
+			fmt.Sprintf("cannot be used to create the Namespaces of the Spaces: %s", msg)))
+	}
+	if len(allErrs) > 0 || webhookClient == nil {
+		return allErrs, nil
+	}
+
+	namespace := &corev1.Namespace{}
+	err := webhookClient.Get(context.Background(), client.ObjectKey{Name: spacesNamespace}, namespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return allErrs, nil
+		}
+		return allErrs, err
+	}
+	if !isOwnedByOrganization(namespace, r.Name) {
+		allErrs = append(allErrs, field.Forbidden(namePath,
+			fmt.Sprintf("the Namespace %s already exists and is not managed by the operator", spacesNamespace)))
+	}
+
+	return allErrs, nil
+}
+
+// isOwnedByOrganization returns true when the Namespace is controlled by an
+// Organization with the given name
+func isOwnedByOrganization(namespace *corev1.Namespace, organizationName string) bool {
+	owner := metav1.GetControllerOf(namespace)
+	return owner != nil &&
+		owner.Kind == "Organization" &&
+		owner.Name == organizationName &&
+		strings.HasPrefix(owner.APIVersion, GroupVersion.Group+"/")
+}
+
+// validateNamespaceMetadata checks the labels and the annotations to add to
+// the Namespaces of the Organization
+func (r *Organization) validateNamespaceMetadata() field.ErrorList {
+	specPath := field.NewPath("spec")
+	allErrs := validateLabels(r.Spec.DefaultNamespaceLabels, specPath.Child("defaultNamespaceLabels"))
+	allErrs = append(allErrs, validateAnnotations(r.Spec.DefaultNamespaceAnnotations, specPath.Child("defaultNamespaceAnnotations"))...)
+	return allErrs
+}
+
+// validateMembers checks the members granted access to the Spaces of the
+// Organization
+func (r *Organization) validateMembers() field.ErrorList {
+	specPath := field.NewPath("spec")
+	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, validateMemberList(r.Spec.Admins, specPath.Child("admins"))...)
+	allErrs = append(allErrs, validateMemberList(r.Spec.Editors, specPath.Child("editors"))...)
+	allErrs = append(allErrs, validateMemberList(r.Spec.Viewers, specPath.Child("viewers"))...)
+	allErrs = append(allErrs, validateMemberList(r.Spec.AdminGroups, specPath.Child("adminGroups"))...)
+	allErrs = append(allErrs, validateMemberList(r.Spec.EditorGroups, specPath.Child("editorGroups"))...)
+	allErrs = append(allErrs, validateMemberList(r.Spec.ViewerGroups, specPath.Child("viewerGroups"))...)
+	allErrs = append(allErrs, validateServiceAccounts(r.Spec.AdminServiceAccounts, true, specPath.Child("adminServiceAccounts"))...)
+	allErrs = append(allErrs, validateServiceAccounts(r.Spec.EditorServiceAccounts, true, specPath.Child("editorServiceAccounts"))...)
+	allErrs = append(allErrs, validateServiceAccounts(r.Spec.ViewerServiceAccounts, true, specPath.Child("viewerServiceAccounts"))...)
+	return allErrs
+}
+
+// validateBudget ensures the quantities of the budget are not negative.
+// When checkAllocation is true the budget must also cover what has already
+// been allocated to the Spaces of the Organization.
+func (r *Organization) validateBudget(checkAllocation bool) (field.ErrorList, error) {
+	allErrs := field.ErrorList{}
+	budgetPath := field.NewPath("spec", "budget")
+	for name, quantity := range r.Spec.Budget {
+		if quantity.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(budgetPath.Key(string(name)),
+				quantity.String(), "must be greater than or equal to 0"))
+		}
+	}
+
+	if len(allErrs) == 0 && checkAllocation && len(r.Spec.Budget) > 0 && webhookClient != nil {
+		spacesNamespace := common.ComputeSpacesNamespaceFromOrganizationName(r.Name)
+		spaces := &SpaceList{}
+		err := webhookClient.List(context.Background(), spaces, client.InNamespace(spacesNamespace))
+		if err != nil {
+			return allErrs, err
+		}
+		extraConfigs := &SpaceExtraConfigList{}
+		err = webhookClient.List(context.Background(), extraConfigs, client.InNamespace(spacesNamespace))
+		if err != nil {
+			return allErrs, err
+		}
+
+		allocated := AllocatedBudget(r.Spec.Budget, spaces.Items, extraConfigs.Items)
+		for name, quantity := range r.Spec.Budget {
+			used := allocated[name]
+			if used.Cmp(quantity) > 0 {
+				allErrs = append(allErrs, field.Forbidden(budgetPath.Key(string(name)),
+					fmt.Sprintf("the Spaces of the Organization already allocate %s", used.String())))
+			}
+		}
+	}
+
+	return allErrs, nil
+}
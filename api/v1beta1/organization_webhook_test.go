@@ -0,0 +1,174 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+func TestValidateOrganizationName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		errField string
+	}{
+		{"valid", "acme", ""},
+		{"reserved", "kube-public", "metadata.name"},
+		{"63 characters", strings.Repeat("a", 63), ""},
+		{"64 characters", strings.Repeat("a", 64), "metadata.name"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			organization := &Organization{ObjectMeta: metav1.ObjectMeta{Name: test.input}}
+			allErrs, err := organization.validateName()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertFieldError(t, allErrs, test.errField)
+		})
+	}
+}
+
+func TestOrganizationDefault(t *testing.T) {
+	organization := &Organization{ObjectMeta: metav1.ObjectMeta{Name: "acme"}}
+	organization.Default()
+	if organization.Spec.DeletionPolicy != OrganizationDeletionPolicyCascade {
+		t.Errorf("expected deletion policy %s, got %s",
+			OrganizationDeletionPolicyCascade, organization.Spec.DeletionPolicy)
+	}
+	expected := []string{common.OrganizationFinalizer}
+	if !reflect.DeepEqual(organization.GetFinalizers(), expected) {
+		t.Errorf("expected finalizers %v, got %v", expected, organization.GetFinalizers())
+	}
+
+	// Defaulting again changes nothing
+	organization.Spec.DeletionPolicy = OrganizationDeletionPolicyBlock
+	organization.Default()
+	if organization.Spec.DeletionPolicy != OrganizationDeletionPolicyBlock {
+		t.Errorf("expected deletion policy %s, got %s",
+			OrganizationDeletionPolicyBlock, organization.Spec.DeletionPolicy)
+	}
+	if !reflect.DeepEqual(organization.GetFinalizers(), expected) {
+		t.Errorf("expected finalizers %v, got %v", expected, organization.GetFinalizers())
+	}
+}
+
+func TestValidateOrganizationSpacesNamespace(t *testing.T) {
+	controller := true
+	namespaces := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "legacy-spaces"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name: "acme-spaces",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: GroupVersion.String(),
+				Kind:       "Organization",
+				Name:       "acme",
+				Controller: &controller,
+			}},
+		}},
+	}
+	defer useWebhookClient(namespaces...)()
+
+	tests := []struct {
+		name     string
+		errField string
+	}{
+		{"brand-new", ""},
+		{"acme", ""},
+		{"legacy", "metadata.name"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			organization := &Organization{ObjectMeta: metav1.ObjectMeta{Name: test.name}}
+			allErrs, err := organization.validateName()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertFieldError(t, allErrs, test.errField)
+		})
+	}
+}
+
+func TestValidateOrganizationNamespaceMetadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		errField    string
+	}{
+		{"valid", map[string]string{"team": "web"}, map[string]string{"example.com/owner": "Web team"}, ""},
+		{"invalid label key", map[string]string{"team name": "web"}, nil, "spec.defaultNamespaceLabels"},
+		{"invalid label value", map[string]string{"team": "web team"}, nil, "spec.defaultNamespaceLabels[team]"},
+		{"reserved label key", map[string]string{common.ReservedKeyPrefix + "space": "web"}, nil,
+			"spec.defaultNamespaceLabels[" + common.ReservedKeyPrefix + "space]"},
+		{"reserved annotation key", nil, map[string]string{common.ReservedKeyPrefix + "space": "web"},
+			"spec.defaultNamespaceAnnotations[" + common.ReservedKeyPrefix + "space]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			organization := &Organization{
+				ObjectMeta: metav1.ObjectMeta{Name: "acme"},
+				Spec: OrganizationSpec{
+					DefaultNamespaceLabels:      test.labels,
+					DefaultNamespaceAnnotations: test.annotations,
+				},
+			}
+			assertFieldError(t, organization.validateNamespaceMetadata(), test.errField)
+		})
+	}
+}
+
+func TestValidateOrganization(t *testing.T) {
+	defer useWebhookClient()()
+
+	organization := &Organization{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec:       OrganizationSpec{Admins: []string{"alice", "alice"}},
+	}
+	err := organization.ValidateCreate()
+	if !apierrors.IsInvalid(err) {
+		t.Fatalf("expected the Organization to be invalid, got %v", err)
+	}
+	fields := []string{}
+	for _, cause := range err.(*apierrors.StatusError).ErrStatus.Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	sort.Strings(fields)
+	expected := []string{"metadata.name", "spec.admins[1]"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected errors about %v, got %v", expected, fields)
+	}
+
+	// The name is checked only on creation
+	organization.Spec.Admins = []string{"alice"}
+	if err = organization.ValidateUpdate(organization.DeepCopy()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,377 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// SpaceSpec defines the desired state of Space
+type SpaceSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// Optional names of groups with admin rights
+	// +optional
+	AdminGroups []string `json:"adminGroups,omitempty"`
+
+	// Optional names of groups with edit rights
+	// +optional
+	EditorGroups []string `json:"editorGroups,omitempty"`
+
+	// optional names of groups with view rights
+	// +optional
+	ViewerGroups []string `json:"viewerGroups,omitempty"`
+
+	// Optional names of users with admin rights
+	// +optional
+	Admins []string `json:"admins,omitempty"`
+
+	// Optional names of users with edit rights
+	// +optional
+	Editors []string `json:"editors,omitempty"`
+
+	// Optional names of users with view rights
+	// +optional
+	Viewers []string `json:"viewers,omitempty"`
+
+	// Optional ServiceAccounts with admin rights, in the "namespace/name"
+	// format. The Namespace of the Space is used when the namespace is
+	// omitted.
+	// +optional
+	AdminServiceAccounts []string `json:"adminServiceAccounts,omitempty"`
+
+	// Optional ServiceAccounts with edit rights, in the "namespace/name"
+	// format. The Namespace of the Space is used when the namespace is
+	// omitted.
+	// +optional
+	EditorServiceAccounts []string `json:"editorServiceAccounts,omitempty"`
+
+	// Optional ServiceAccounts with view rights, in the "namespace/name"
+	// format. The Namespace of the Space is used when the namespace is
+	// omitted.
+	// +optional
+	ViewerServiceAccounts []string `json:"viewerServiceAccounts,omitempty"`
+
+	// Optional map with additional labels to add to the Namespace of the
+	// Space. They take precedence over the default ones of the
+	// Organization.
+	// +optional
+	NamespaceLabels map[string]string `json:"namespaceLabels,omitempty"`
+
+	// Optional map with additional annotations to add to the Namespace of
+	// the Space. They take precedence over the default ones of the
+	// Organization.
+	// +optional
+	NamespaceAnnotations map[string]string `json:"namespaceAnnotations,omitempty"`
+
+	// Optional quota enforced inside of the Namespace of the Space
+	// +optional
+	Quota *SpaceQuota `json:"quota,omitempty"`
+
+	// Optional defaults and constraints for the compute resources of the
+	// containers running inside of the Namespace of the Space. They take
+	// precedence over the defaults of the Organization.
+	// +optional
+	ContainerLimits *ContainerLimits `json:"containerLimits,omitempty"`
+
+	// Optional network isolation of the Namespace of the Space, the one
+	// of the Organization is used when empty
+	// +optional
+	NetworkIsolation NetworkIsolation `json:"networkIsolation,omitempty"`
+
+	// What happens to the Namespace of the Space when the Space is
+	// deleted. Delete removes the Namespace and everything inside of it.
+	// Retain removes the objects and the labels set by the operator,
+	// keeping the workloads. Orphan leaves the Namespace untouched.
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy SpaceDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Name of an existing Namespace to adopt instead of creating a new one.
+	// The operator adds its labels and RoleBindings to the Namespace,
+	// keeping track of what it changed so the adoption can be undone by
+	// deleting the Space with the Retain policy.
+	// +optional
+	AdoptNamespace string `json:"adoptNamespace,omitempty"`
+
+	// Optional additional RoleBindings to create inside of the Namespace
+	// of the Space
+	// +optional
+	RoleBindings []SpaceRoleBinding `json:"roleBindings,omitempty"`
+}
+
+// SpaceRoleBinding grants a ClusterRole to a set of users, groups and
+// ServiceAccounts inside of the Namespace of the Space
+type SpaceRoleBinding struct {
+	// Name of the RoleBinding, it cannot be one of the names used by the
+	// operator: administrators, editors and viewers
+	Name string `json:"name"`
+
+	// Name of the ClusterRole to grant
+	ClusterRole string `json:"clusterRole"`
+
+	// Optional names of the users holding the ClusterRole
+	// +optional
+	Users []string `json:"users,omitempty"`
+
+	// Optional names of the groups holding the ClusterRole
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// Optional ServiceAccounts holding the ClusterRole, in the
+	// "namespace/name" format. The Namespace of the Space is used when
+	// the namespace is omitted.
+	// +optional
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
+}
+
+// SpaceDeletionPolicy defines what happens to the Namespace of a Space when
+// the Space is deleted
+// +kubebuilder:validation:Enum=Delete;Retain;Orphan
+type SpaceDeletionPolicy string
+
+const (
+	// SpaceDeletionPolicyDelete deletes the Namespace of the Space
+	SpaceDeletionPolicyDelete SpaceDeletionPolicy = "Delete"
+	// SpaceDeletionPolicyRetain keeps the Namespace of the Space, removing
+	// the objects and the labels set by the operator
+	SpaceDeletionPolicyRetain SpaceDeletionPolicy = "Retain"
+	// SpaceDeletionPolicyOrphan leaves the Namespace of the Space untouched
+	SpaceDeletionPolicyOrphan SpaceDeletionPolicy = "Orphan"
+)
+
+// NetworkIsolation defines which Namespaces can send traffic to the pods
+// running inside of the Namespace of a Space
+// +kubebuilder:validation:Enum=none;space;organization
+type NetworkIsolation string
+
+const (
+	// NetworkIsolationNone allows traffic from all the Namespaces
+	NetworkIsolationNone NetworkIsolation = "none"
+	// NetworkIsolationSpace allows only the traffic coming from the
+	// Namespace of the Space
+	NetworkIsolationSpace NetworkIsolation = "space"
+	// NetworkIsolationOrganization allows only the traffic coming from the
+	// Namespaces of the Organization
+	NetworkIsolationOrganization NetworkIsolation = "organization"
+)
+
+// ContainerLimits defines the compute resources of the containers running
+// inside of the Namespace of a Space. It is enforced through a LimitRange.
+type ContainerLimits struct {
+	// Requests assigned to the containers that do not specify them
+	// +optional
+	DefaultRequest corev1.ResourceList `json:"defaultRequest,omitempty"`
+
+	// Limits assigned to the containers that do not specify them
+	// +optional
+	Default corev1.ResourceList `json:"default,omitempty"`
+
+	// Minimum amount of resources a container can request
+	// +optional
+	Min corev1.ResourceList `json:"min,omitempty"`
+
+	// Maximum amount of resources a container can be limited to
+	// +optional
+	Max corev1.ResourceList `json:"max,omitempty"`
+}
+
+// MergeContainerLimits returns new ContainerLimits made of base overridden,
+// resource by resource, by override. nil is returned when both are nil.
+func MergeContainerLimits(base, override *ContainerLimits) *ContainerLimits {
+	if base == nil && override == nil {
+		return nil
+	}
+
+	merged := &ContainerLimits{}
+	for _, limits := range []*ContainerLimits{base, override} {
+		if limits == nil {
+			continue
+		}
+		merged.DefaultRequest = mergeResourceLists(merged.DefaultRequest, limits.DefaultRequest)
+		merged.Default = mergeResourceLists(merged.Default, limits.Default)
+		merged.Min = mergeResourceLists(merged.Min, limits.Min)
+		merged.Max = mergeResourceLists(merged.Max, limits.Max)
+	}
+	return merged
+}
+
+// LimitRangeItem returns the LimitRange entry enforcing the limits on
+// containers
+func (l *ContainerLimits) LimitRangeItem() corev1.LimitRangeItem {
+	return corev1.LimitRangeItem{
+		Type:           corev1.LimitTypeContainer,
+		DefaultRequest: l.DefaultRequest.DeepCopy(),
+		Default:        l.Default.DeepCopy(),
+		Min:            l.Min.DeepCopy(),
+		Max:            l.Max.DeepCopy(),
+	}
+}
+
+func mergeResourceLists(base, override corev1.ResourceList) corev1.ResourceList {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	merged := base.DeepCopy()
+	if merged == nil {
+		merged = corev1.ResourceList{}
+	}
+	for name, quantity := range override {
+		merged[name] = quantity.DeepCopy()
+	}
+	return merged
+}
+
+// SpaceQuota defines the resources the Space is allowed to consume. It is
+// enforced through a ResourceQuota created inside of the Namespace of the
+// Space.
+type SpaceQuota struct {
+	// Hard limits for compute resources, storage and object counts
+	// (eg: "requests.cpu", "requests.storage", "pods")
+	// +optional
+	Hard corev1.ResourceList `json:"hard,omitempty"`
+
+	// Optional scopes the quota applies to, all the objects of the
+	// Namespace are tracked when empty
+	// +optional
+	Scopes []corev1.ResourceQuotaScope `json:"scopes,omitempty"`
+
+	// Optional selector, on top of Scopes, of the objects the quota
+	// applies to
+	// +optional
+	ScopeSelector *corev1.ScopeSelector `json:"scopeSelector,omitempty"`
+}
+
+// ResourceQuotaSpec returns the spec of the ResourceQuota enforcing the quota
+func (q *SpaceQuota) ResourceQuotaSpec() *corev1.ResourceQuotaSpec {
+	spec := &corev1.ResourceQuotaSpec{
+		Hard:   q.Hard.DeepCopy(),
+		Scopes: append([]corev1.ResourceQuotaScope{}, q.Scopes...),
+	}
+	if q.ScopeSelector != nil {
+		spec.ScopeSelector = q.ScopeSelector.DeepCopy()
+	}
+	return spec
+}
+
+// SpacePhase is a label for the condition of a Space at the current time
+type SpacePhase string
+
+const (
+	// SpacePhasePending means the resources of the Space are not ready yet
+	SpacePhasePending SpacePhase = "Pending"
+	// SpacePhaseReady means all the resources of the Space are in place
+	SpacePhaseReady SpacePhase = "Ready"
+	// SpacePhaseTerminating means the Space is being deleted
+	SpacePhaseTerminating SpacePhase = "Terminating"
+	// SpacePhaseError means the last reconciliation of the Space failed
+	SpacePhaseError SpacePhase = "Error"
+)
+
+const (
+	// SpaceConditionNamespaceReady is True when the Namespace associated
+	// with the Space exists and has the right set of labels
+	SpaceConditionNamespaceReady = "NamespaceReady"
+	// SpaceConditionRBACReady is True when all the RoleBindings associated
+	// with the Space are in place
+	SpaceConditionRBACReady = "RBACReady"
+	// SpaceConditionOrganizationFound is True when the Organization owning
+	// the Space exists
+	SpaceConditionOrganizationFound = "OrganizationFound"
+	// SpaceConditionQuotaReady is True when the ResourceQuota of the Space
+	// matches the desired quota, or has been removed when no quota is
+	// required
+	SpaceConditionQuotaReady = "QuotaReady"
+	// SpaceConditionLimitRangeReady is True when the LimitRange of the Space
+	// matches the desired container limits, or has been removed when no
+	// limits are required
+	SpaceConditionLimitRangeReady = "LimitRangeReady"
+	// SpaceConditionNetworkPolicyReady is True when the NetworkPolicy of the
+	// Space matches the desired network isolation
+	SpaceConditionNetworkPolicyReady = "NetworkPolicyReady"
+)
+
+// SpaceStatus defines the observed state of Space
+type SpaceStatus struct {
+	// Name of the Namespace managed by the Space
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Current phase of the Space
+	// +optional
+	Phase SpacePhase `json:"phase,omitempty"`
+
+	// Latest available observations of the state of the Space
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// The generation observed by the Space controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Error that caused the last reconciliation to fail, empty when the
+	// last reconciliation was successful
+	// +optional
+	LastReconcileError string `json:"lastReconcileError,omitempty"`
+
+	// Finalizers blocking the termination of the Namespace of a Space
+	// being deleted
+	// +optional
+	NamespaceFinalizers []string `json:"namespaceFinalizers,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Namespace",type="string",JSONPath=".status.namespace"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Space is the Schema for the spaces API
+type Space struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SpaceSpec   `json:"spec,omitempty"`
+	Status SpaceStatus `json:"status,omitempty"`
+}
+
+// NamespaceName returns the name of the Namespace managed by the Space,
+// given the name of the Organization owning it
+func (s *Space) NamespaceName(organizationName string) string {
+	if s.Spec.AdoptNamespace != "" {
+		return s.Spec.AdoptNamespace
+	}
+	return common.NameOfNamespaceCreateBySpace(organizationName, s.Name)
+}
+
+// +kubebuilder:object:root=true
+
+// SpaceList contains a list of Space
+type SpaceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Space `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Space{}, &SpaceList{})
+}
@@ -0,0 +1,388 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// log is for logging in this package.
+var spacelog = logf.Log.WithName("space-resource")
+
+// webhookClient is used by the validating webhooks to look up the objects
+// related with the one being validated
+var webhookClient client.Client
+
+// SetupWebhookWithManager registers the webhooks of Space. The validating
+// webhook needs the user making the request, hence it's not built on top of
+// webhook.Validator.
+func (r *Space) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookClient = mgr.GetClient()
+	mgr.GetWebhookServer().Register("/validate-k8s-suse-com-v1beta1-space",
+		&webhook.Admission{Handler: &requestValidatingHandler{object: r}})
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+
+// +kubebuilder:webhook:path=/mutate-k8s-suse-com-v1beta1-space,mutating=true,failurePolicy=fail,groups=k8s.suse.com,resources=spaces,verbs=create;update,versions=v1beta1,name=mspace.v1beta1.kb.io
+
+var _ webhook.Defaulter = &Space{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+// Mutation webhook for Space objects. It ensures a proper finalizer is set.
+func (r *Space) Default() {
+	spacelog.Info("Setting default values for Space object",
+		"Namespace", r.Namespace,
+		"Name", r.Name)
+	spaceFinalizerFound := false
+
+	finalizers := r.ObjectMeta.GetFinalizers()
+	for _, finalizer := range finalizers {
+		if finalizer == common.SpaceFinalizer {
+			spaceFinalizerFound = true
+		}
+	}
+
+	if !spaceFinalizerFound {
+		finalizers = append(finalizers, common.SpaceFinalizer)
+		r.SetFinalizers(finalizers)
+	}
+
+	// An adopted Namespace existed before the Space, don't delete it
+	// together with the Space unless explicitly asked to
+	if r.Spec.AdoptNamespace != "" && r.Spec.DeletionPolicy == "" {
+		r.Spec.DeletionPolicy = SpaceDeletionPolicyRetain
+	}
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-k8s-suse-com-v1beta1-space,mutating=false,failurePolicy=fail,groups=k8s.suse.com,resources=spaces,versions=v1beta1,name=vspace.v1beta1.kb.io
+
+var _ requestValidator = &Space{}
+
+// validateRequest implements requestValidator
+func (r *Space) validateRequest(ctx context.Context, user authenticationv1.UserInfo, old runtime.Object) error {
+	spacelog.Info("Validating Space object",
+		"Namespace", r.Namespace,
+		"Name", r.Name,
+		"User", user.Username)
+	if r.GetDeletionTimestamp() != nil {
+		// Never prevent the removal of the finalizers
+		return nil
+	}
+	oldSpace, _ := old.(*Space)
+	creating := oldSpace == nil
+
+	allErrs := r.validateMembers()
+	allErrs = append(allErrs, validateLabels(r.Spec.NamespaceLabels, field.NewPath("spec", "namespaceLabels"))...)
+	allErrs = append(allErrs, validateAnnotations(r.Spec.NamespaceAnnotations, field.NewPath("spec", "namespaceAnnotations"))...)
+
+	if creating {
+		// The name of the Namespace is shortened when needed, the labels
+		// holding the name of the Space are not
+		allErrs = append(allErrs, validateLabelValueName(r.Name, field.NewPath("metadata", "name"))...)
+	}
+	if !creating && oldSpace.Spec.AdoptNamespace != r.Spec.AdoptNamespace {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "adoptNamespace"),
+			"cannot be changed"))
+	}
+
+	organization, organizationErrs, err := r.validateOrganization(creating)
+	if err != nil {
+		return err
+	}
+	allErrs = append(allErrs, organizationErrs...)
+
+	if creating && r.Spec.AdoptNamespace != "" {
+		adoptionErrs, err := r.validateAdoption(organization)
+		if err != nil {
+			return err
+		}
+		allErrs = append(allErrs, adoptionErrs...)
+	}
+
+	// The budget is checked only when the quota changes: Spaces created
+	// before the budget was set can still be changed
+	if organization != nil && (creating || !equality.Semantic.DeepEqual(oldSpace.Spec.Quota, r.Spec.Quota)) {
+		budgetErrs, err := r.validateBudget(organization)
+		if err != nil {
+			return err
+		}
+		allErrs = append(allErrs, budgetErrs...)
+	}
+
+	if len(allErrs) == 0 && webhookClient != nil {
+		authorizationErrs, err := r.authorizeRoleBindings(ctx, user, oldSpace)
+		if err != nil {
+			return err
+		}
+		allErrs = append(allErrs, authorizationErrs...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Space").GroupKind(), r.Name, allErrs)
+}
+
+// authorizeRoleBindings ensures the user is allowed to bind the ClusterRoles
+// of the roleBindings field: the operator creates the RoleBindings, it
+// would otherwise grant any ClusterRole to the members of the Space. The bind
+// permission is checked inside of the Namespace of the Space object.
+// RoleBindings not changed by an update are not checked again.
+func (r *Space) authorizeRoleBindings(ctx context.Context, user authenticationv1.UserInfo, old *Space) (field.ErrorList, error) {
+	allErrs := field.ErrorList{}
+	for i, roleBinding := range r.Spec.RoleBindings {
+		if old != nil && oldSpaceRoleBindingUnchanged(old, roleBinding) {
+			continue
+		}
+
+		allowed, err := userCan(ctx, user, authorizationv1.ResourceAttributes{
+			Namespace: r.Namespace,
+			Verb:      "bind",
+			Group:     rbac.GroupName,
+			Resource:  "clusterroles",
+			Name:      roleBinding.ClusterRole,
+		})
+		if err != nil {
+			return allErrs, err
+		}
+		if !allowed {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "roleBindings").Index(i).Child("clusterRole"),
+				fmt.Sprintf("user %s cannot bind ClusterRole %s inside of the Namespace %s",
+					user.Username, roleBinding.ClusterRole, r.Namespace)))
+		}
+	}
+	return allErrs, nil
+}
+
+// oldSpaceRoleBindingUnchanged returns true when the old Space already
+// defines the same RoleBinding
+func oldSpaceRoleBindingUnchanged(old *Space, roleBinding SpaceRoleBinding) bool {
+	for _, oldRoleBinding := range old.Spec.RoleBindings {
+		if equality.Semantic.DeepEqual(oldRoleBinding, roleBinding) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateOrganization checks the Space is defined inside of the Namespace
+// of an Organization and its name can be used to create the Namespace of the
+// Space. When creating is true the Organization must exist and must not be
+// being deleted. The Organization is returned when it could be found.
+func (r *Space) validateOrganization(creating bool) (*Organization, field.ErrorList, error) {
+	allErrs := field.ErrorList{}
+	namespacePath := field.NewPath("metadata", "namespace")
+
+	if webhookClient == nil {
+		return nil, allErrs, nil
+	}
+
+	organizationName, found, err := common.OrganizationOfSpacesNamespace(webhookClient, r.Namespace, context.Background())
+	if err != nil {
+		return nil, allErrs, err
+	}
+	if !found {
+		allErrs = append(allErrs, field.Invalid(namespacePath, r.Namespace,
+			"Spaces must be created inside of the Namespace holding the Spaces of an Organization"))
+		return nil, allErrs, nil
+	}
+
+	if creating && r.Spec.AdoptNamespace == "" {
+		namespaceName := r.NamespaceName(organizationName)
+		for _, msg := range validation.IsDNS1123Label(namespaceName) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("metadata", "name"), r.Name,
+				fmt.Sprintf("cannot be used to create the Namespace %s: %s", namespaceName, msg)))
+		}
+	}
+
+	organization := &Organization{}
+	err = webhookClient.Get(context.Background(), client.ObjectKey{Name: organizationName}, organization)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, allErrs, err
+		}
+		if creating {
+			allErrs = append(allErrs, field.Invalid(namespacePath, r.Namespace,
+				fmt.Sprintf("Organization %s does not exist", organizationName)))
+		}
+		return nil, allErrs, nil
+	}
+
+	if creating && organization.GetDeletionTimestamp() != nil {
+		allErrs = append(allErrs, field.Forbidden(namespacePath,
+			fmt.Sprintf("Organization %s is being deleted", organizationName)))
+	}
+
+	return organization, allErrs, nil
+}
+
+// validateAdoption checks the Namespace adopted by the Space is not a system
+// one or one of the Namespaces created for an Organization. An existing
+// Namespace must have been made adoptable by the Organization: the operator
+// would otherwise grant the members of the Space access to any Namespace.
+func (r *Space) validateAdoption(organization *Organization) (field.ErrorList, error) {
+	allErrs := field.ErrorList{}
+	adoptPath := field.NewPath("spec", "adoptNamespace")
+	name := r.Spec.AdoptNamespace
+
+	for _, msg := range validation.IsDNS1123Label(name) {
+		allErrs = append(allErrs, field.Invalid(adoptPath, name, msg))
+	}
+	if name == "kube" || name == "default" || strings.HasPrefix(name, "kube-") {
+		allErrs = append(allErrs, field.Forbidden(adoptPath,
+			fmt.Sprintf("%s is reserved", name)))
+	}
+	if len(allErrs) > 0 || webhookClient == nil {
+		return allErrs, nil
+	}
+
+	namespace := &corev1.Namespace{}
+	err := webhookClient.Get(context.Background(), client.ObjectKey{Name: name}, namespace)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return allErrs, nil
+		}
+		return allErrs, err
+	}
+	if owner := metav1.GetControllerOf(namespace); owner != nil && owner.Kind == "Organization" {
+		allErrs = append(allErrs, field.Forbidden(adoptPath,
+			fmt.Sprintf("the Namespace %s is managed by Organization %s", name, owner.Name)))
+		return allErrs, nil
+	}
+	if organization != nil && namespace.GetAnnotations()[common.AdoptableByAnnotation] != organization.Name {
+		allErrs = append(allErrs, field.Forbidden(adoptPath,
+			fmt.Sprintf("the Namespace %s must be annotated with %s=%s before it can be adopted",
+				name, common.AdoptableByAnnotation, organization.Name)))
+	}
+
+	return allErrs, nil
+}
+
+// validateMembers checks the members granted access to the Space
+func (r *Space) validateMembers() field.ErrorList {
+	specPath := field.NewPath("spec")
+	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, validateMemberList(r.Spec.Admins, specPath.Child("admins"))...)
+	allErrs = append(allErrs, validateMemberList(r.Spec.Editors, specPath.Child("editors"))...)
+	allErrs = append(allErrs, validateMemberList(r.Spec.Viewers, specPath.Child("viewers"))...)
+	allErrs = append(allErrs, validateMemberList(r.Spec.AdminGroups, specPath.Child("adminGroups"))...)
+	allErrs = append(allErrs, validateMemberList(r.Spec.EditorGroups, specPath.Child("editorGroups"))...)
+	allErrs = append(allErrs, validateMemberList(r.Spec.ViewerGroups, specPath.Child("viewerGroups"))...)
+	allErrs = append(allErrs, validateServiceAccounts(r.Spec.AdminServiceAccounts, false, specPath.Child("adminServiceAccounts"))...)
+	allErrs = append(allErrs, validateServiceAccounts(r.Spec.EditorServiceAccounts, false, specPath.Child("editorServiceAccounts"))...)
+	allErrs = append(allErrs, validateServiceAccounts(r.Spec.ViewerServiceAccounts, false, specPath.Child("viewerServiceAccounts"))...)
+
+	roleBindingNames := map[string]bool{}
+	for i, roleBinding := range r.Spec.RoleBindings {
+		path := specPath.Child("roleBindings").Index(i)
+		switch {
+		case roleBinding.Name == "":
+			allErrs = append(allErrs, field.Required(path.Child("name"), "must not be empty"))
+		case common.IsBuiltinRoleBindingName(roleBinding.Name):
+			allErrs = append(allErrs, field.Forbidden(path.Child("name"),
+				fmt.Sprintf("%s is reserved by the operator", roleBinding.Name)))
+		case roleBindingNames[roleBinding.Name]:
+			allErrs = append(allErrs, field.Duplicate(path.Child("name"), roleBinding.Name))
+		default:
+			for _, msg := range validation.IsDNS1123Subdomain(roleBinding.Name) {
+				allErrs = append(allErrs, field.Invalid(path.Child("name"), roleBinding.Name, msg))
+			}
+		}
+		roleBindingNames[roleBinding.Name] = true
+
+		if roleBinding.ClusterRole == "" {
+			allErrs = append(allErrs, field.Required(path.Child("clusterRole"), "must not be empty"))
+		}
+		allErrs = append(allErrs, validateMemberList(roleBinding.Users, path.Child("users"))...)
+		allErrs = append(allErrs, validateMemberList(roleBinding.Groups, path.Child("groups"))...)
+		allErrs = append(allErrs, validateServiceAccounts(roleBinding.ServiceAccounts, false, path.Child("serviceAccounts"))...)
+	}
+	return allErrs
+}
+
+// validateBudget ensures the quota of the Space, merged with the one of the
+// SpaceExtraConfig objects applying to it, doesn't push the resources
+// allocated by the Spaces of the Organization over the Organization budget
+func (r *Space) validateBudget(organization *Organization) (field.ErrorList, error) {
+	allErrs := field.ErrorList{}
+	if len(organization.Spec.Budget) == 0 {
+		return allErrs, nil
+	}
+	organizationName := organization.Name
+
+	spaces := &SpaceList{}
+	if err := webhookClient.List(context.Background(), spaces, client.InNamespace(r.Namespace)); err != nil {
+		return allErrs, err
+	}
+	extraConfigs := &SpaceExtraConfigList{}
+	if err := webhookClient.List(context.Background(), extraConfigs, client.InNamespace(r.Namespace)); err != nil {
+		return allErrs, err
+	}
+	others := []Space{}
+	for _, space := range spaces.Items {
+		if space.Name != r.Name {
+			others = append(others, space)
+		}
+	}
+	allocated := AllocatedBudget(organization.Spec.Budget, others, extraConfigs.Items)
+	hard := hardLimitsOfSpace(r, extraConfigs.Items)
+
+	hardPath := field.NewPath("spec", "quota", "hard")
+	for name, budget := range organization.Spec.Budget {
+		quantity, found := hard[name]
+		if !found {
+			allErrs = append(allErrs, field.Required(hardPath.Key(string(name)),
+				fmt.Sprintf("Organization %s has a budget for %s", organizationName, name)))
+			continue
+		}
+
+		total := allocated[name]
+		total.Add(quantity)
+		if total.Cmp(budget) > 0 {
+			available := budget.DeepCopy()
+			available.Sub(allocated[name])
+			allErrs = append(allErrs, field.Forbidden(hardPath.Key(string(name)),
+				fmt.Sprintf("exceeds the budget of Organization %s: %s requested, %s available",
+					organizationName, quantity.String(), available.String())))
+		}
+	}
+
+	return allErrs, nil
+}
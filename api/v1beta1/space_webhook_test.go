@@ -0,0 +1,418 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// fakeClient serves Get and List requests from a fixed set of objects and
+// answers the SubjectAccessReviews using authorize, the other methods of
+// client.Client are not implemented
+type fakeClient struct {
+	client.Client
+	objects []runtime.Object
+	// authorize decides the SubjectAccessReviews, they are all denied
+	// when nil
+	authorize func(spec authorizationv1.SubjectAccessReviewSpec) bool
+}
+
+func (c *fakeClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	for _, object := range c.objects {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return err
+		}
+		if reflect.TypeOf(object) == reflect.TypeOf(obj) &&
+			accessor.GetName() == key.Name && accessor.GetNamespace() == key.Namespace {
+			reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(object.DeepCopyObject()).Elem())
+			return nil
+		}
+	}
+	return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+}
+
+func (c *fakeClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	options := (&client.ListOptions{}).ApplyOptions(opts)
+	itemType := reflect.ValueOf(list).Elem().FieldByName("Items").Type().Elem()
+
+	items := []runtime.Object{}
+	for _, object := range c.objects {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return err
+		}
+		if reflect.TypeOf(object).Elem() == itemType &&
+			(options.Namespace == "" || accessor.GetNamespace() == options.Namespace) {
+			items = append(items, object.DeepCopyObject())
+		}
+	}
+	return meta.SetList(list, items)
+}
+
+func (c *fakeClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	review, ok := obj.(*authorizationv1.SubjectAccessReview)
+	if !ok {
+		return fmt.Errorf("cannot create %T", obj)
+	}
+	review.Status.Allowed = c.authorize != nil && c.authorize(review.Spec)
+	return nil
+}
+
+// useWebhookClient makes the webhooks use a fakeClient holding the given
+// objects, the returned function restores the previous client
+func useWebhookClient(objects ...runtime.Object) func() {
+	previous := webhookClient
+	webhookClient = &fakeClient{objects: objects}
+	return func() {
+		webhookClient = previous
+	}
+}
+
+func TestValidateAdoption(t *testing.T) {
+	controller := true
+	organization := &Organization{ObjectMeta: metav1.ObjectMeta{Name: "acme"}}
+	namespaces := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "legacy"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "adoptable",
+			Annotations: map[string]string{common.AdoptableByAnnotation: "acme"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "adoptable-by-other",
+			Annotations: map[string]string{common.AdoptableByAnnotation: "other"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "other-spaces",
+			Annotations: map[string]string{common.AdoptableByAnnotation: "acme"},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: GroupVersion.String(),
+				Kind:       "Organization",
+				Name:       "other",
+				Controller: &controller,
+			}},
+		}},
+	}
+	defer useWebhookClient(namespaces...)()
+
+	tests := []struct {
+		name      string
+		namespace string
+		errType   field.ErrorType
+	}{
+		{"kube system namespace", "kube-system", field.ErrorTypeForbidden},
+		{"default namespace", "default", field.ErrorTypeForbidden},
+		{"invalid name", "Legacy_App", field.ErrorTypeInvalid},
+		{"namespace of an Organization", "other-spaces", field.ErrorTypeForbidden},
+		{"namespace without opt-in", "legacy", field.ErrorTypeForbidden},
+		{"namespace adoptable by another Organization", "adoptable-by-other", field.ErrorTypeForbidden},
+		{"namespace adoptable by the Organization", "adoptable", ""},
+		{"namespace not existing yet", "brand-new", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			space := &Space{
+				ObjectMeta: metav1.ObjectMeta{Name: "space", Namespace: "acme-spaces"},
+				Spec:       SpaceSpec{AdoptNamespace: test.namespace},
+			}
+			allErrs, err := space.validateAdoption(organization)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.errType == "" {
+				if len(allErrs) > 0 {
+					t.Fatalf("expected no errors, got %v", allErrs)
+				}
+				return
+			}
+			if len(allErrs) == 0 {
+				t.Fatalf("expected a %s error, got none", test.errType)
+			}
+			if allErrs[0].Type != test.errType {
+				t.Errorf("expected a %s error, got %v", test.errType, allErrs)
+			}
+			if allErrs[0].Field != "spec.adoptNamespace" {
+				t.Errorf("expected an error about spec.adoptNamespace, got %v", allErrs)
+			}
+		})
+	}
+}
+
+func TestValidateSpaceName(t *testing.T) {
+	defer useWebhookClient(organizationObjects(nil)...)()
+
+	tests := []struct {
+		name  string
+		input string
+		valid bool
+	}{
+		{"valid", "web", true},
+		{"63 characters", strings.Repeat("a", 63), true},
+		{"64 characters", strings.Repeat("a", 64), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			space := &Space{ObjectMeta: metav1.ObjectMeta{Name: test.input, Namespace: "acme-spaces"}}
+			err := space.validateRequest(context.Background(), authenticationv1.UserInfo{Username: "alice"}, nil)
+			if test.valid {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !apierrors.IsInvalid(err) {
+				t.Fatalf("expected the Space to be invalid, got %v", err)
+			}
+			causes := err.(*apierrors.StatusError).ErrStatus.Details.Causes
+			if len(causes) != 1 || causes[0].Field != "metadata.name" {
+				t.Errorf("expected an error about metadata.name, got %v", causes)
+			}
+		})
+	}
+}
+
+func TestValidateSpaceMembers(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     SpaceSpec
+		errField string
+	}{
+		{"valid members", SpaceSpec{Admins: []string{"alice"}, EditorGroups: []string{"developers"}}, ""},
+		{"empty member", SpaceSpec{Admins: []string{"alice", " "}}, "spec.admins[1]"},
+		{"duplicate member", SpaceSpec{ViewerGroups: []string{"qa", "qa"}}, "spec.viewerGroups[1]"},
+		{"custom RoleBinding", SpaceSpec{RoleBindings: []SpaceRoleBinding{
+			{Name: "deployers", ClusterRole: "deployer", Users: []string{"bob"}},
+		}}, ""},
+		{"reserved RoleBinding name", SpaceSpec{RoleBindings: []SpaceRoleBinding{
+			{Name: "administrators", ClusterRole: "deployer"},
+		}}, "spec.roleBindings[0].name"},
+		{"duplicate RoleBinding name", SpaceSpec{RoleBindings: []SpaceRoleBinding{
+			{Name: "deployers", ClusterRole: "deployer"},
+			{Name: "deployers", ClusterRole: "view"},
+		}}, "spec.roleBindings[1].name"},
+		{"RoleBinding without ClusterRole", SpaceSpec{RoleBindings: []SpaceRoleBinding{
+			{Name: "deployers"},
+		}}, "spec.roleBindings[0].clusterRole"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			space := &Space{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "acme-spaces"},
+				Spec:       test.spec,
+			}
+			assertFieldError(t, space.validateMembers(), test.errField)
+		})
+	}
+}
+
+func TestValidateSpaceOrganization(t *testing.T) {
+	objects := organizationObjects(nil)
+	deleted := objects[0].DeepCopyObject().(*Organization)
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+
+	tests := []struct {
+		name      string
+		objects   []runtime.Object
+		namespace string
+		creating  bool
+		errField  string
+	}{
+		{"Namespace of an Organization", objects, "acme-spaces", true, ""},
+		{"Namespace of no Organization", objects, "default", true, "metadata.namespace"},
+		{"missing Organization", objects[1:], "acme-spaces", true, "metadata.namespace"},
+		{"missing Organization on update", objects[1:], "acme-spaces", false, ""},
+		{"Organization being deleted", []runtime.Object{deleted, objects[1]}, "acme-spaces", true, "metadata.namespace"},
+		{"Organization being deleted on update", []runtime.Object{deleted, objects[1]}, "acme-spaces", false, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer useWebhookClient(test.objects...)()
+			space := &Space{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: test.namespace}}
+			_, allErrs, err := space.validateOrganization(test.creating)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertFieldError(t, allErrs, test.errField)
+		})
+	}
+}
+
+func TestValidateSpaceAdoptNamespaceUpdate(t *testing.T) {
+	defer useWebhookClient(organizationObjects(nil)...)()
+
+	old := &Space{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "acme-spaces"},
+		Spec:       SpaceSpec{AdoptNamespace: "legacy"},
+	}
+	space := old.DeepCopy()
+	space.Spec.Admins = []string{"alice"}
+	user := authenticationv1.UserInfo{Username: "alice"}
+	if err := space.validateRequest(context.Background(), user, old); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	space.Spec.AdoptNamespace = "other"
+	err := space.validateRequest(context.Background(), user, old)
+	if !apierrors.IsInvalid(err) {
+		t.Fatalf("expected the Space to be invalid, got %v", err)
+	}
+	causes := err.(*apierrors.StatusError).ErrStatus.Details.Causes
+	if len(causes) != 1 || causes[0].Field != "spec.adoptNamespace" {
+		t.Errorf("expected an error about spec.adoptNamespace, got %v", causes)
+	}
+}
+
+// allowBinding authorizes the given user to bind only the given ClusterRoles
+// inside of the Namespace acme-spaces
+func allowBinding(username string, clusterRoles ...string) func(authorizationv1.SubjectAccessReviewSpec) bool {
+	return func(spec authorizationv1.SubjectAccessReviewSpec) bool {
+		attributes := spec.ResourceAttributes
+		if spec.User != username || attributes == nil || attributes.Verb != "bind" ||
+			attributes.Resource != "clusterroles" || attributes.Namespace != "acme-spaces" {
+			return false
+		}
+		for _, clusterRole := range clusterRoles {
+			if attributes.Name == clusterRole {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestAuthorizeSpaceRoleBindings(t *testing.T) {
+	defer useWebhookClient()()
+	webhookClient.(*fakeClient).authorize = allowBinding("alice", "deployer")
+
+	user := authenticationv1.UserInfo{Username: "alice"}
+	deployers := SpaceRoleBinding{Name: "deployers", ClusterRole: "deployer", Users: []string{"bob"}}
+	admins := SpaceRoleBinding{Name: "root", ClusterRole: "cluster-admin", Users: []string{"bob"}}
+	moreAdmins := SpaceRoleBinding{Name: "root", ClusterRole: "cluster-admin", Users: []string{"bob", "carol"}}
+
+	tests := []struct {
+		name     string
+		old      []SpaceRoleBinding
+		new      []SpaceRoleBinding
+		errField string
+	}{
+		{"no RoleBindings", nil, nil, ""},
+		{"allowed ClusterRole", nil, []SpaceRoleBinding{deployers}, ""},
+		{"forbidden ClusterRole", nil, []SpaceRoleBinding{deployers, admins}, "spec.roleBindings[1].clusterRole"},
+		{"unchanged RoleBinding", []SpaceRoleBinding{admins}, []SpaceRoleBinding{admins, deployers}, ""},
+		{"changed RoleBinding", []SpaceRoleBinding{admins}, []SpaceRoleBinding{moreAdmins}, "spec.roleBindings[0].clusterRole"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			space := &Space{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "acme-spaces"},
+				Spec:       SpaceSpec{RoleBindings: test.new},
+			}
+			var old *Space
+			if test.old != nil {
+				old = space.DeepCopy()
+				old.Spec.RoleBindings = test.old
+			}
+			allErrs, err := space.authorizeRoleBindings(context.Background(), user, old)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertFieldError(t, allErrs, test.errField)
+		})
+	}
+}
+
+func TestSpaceValidatingHandler(t *testing.T) {
+	defer useWebhookClient(organizationObjects(nil)...)()
+	webhookClient.(*fakeClient).authorize = allowBinding("alice", "deployer")
+
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := &requestValidatingHandler{object: &Space{}}
+	if err = handler.InjectDecoder(decoder); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		user        string
+		clusterRole string
+		allowed     bool
+	}{
+		{"ClusterRole the user can bind", "alice", "deployer", true},
+		{"ClusterRole the user cannot bind", "alice", "cluster-admin", false},
+		{"user without bind permission", "mallory", "deployer", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			space := &Space{
+				TypeMeta:   metav1.TypeMeta{APIVersion: GroupVersion.String(), Kind: "Space"},
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "acme-spaces"},
+				Spec: SpaceSpec{RoleBindings: []SpaceRoleBinding{
+					{Name: "custom", ClusterRole: test.clusterRole, Users: []string{"bob"}},
+				}},
+			}
+			raw, err := json.Marshal(space)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response := handler.Handle(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1beta1.AdmissionRequest{
+					Operation: admissionv1beta1.Create,
+					Object:    runtime.RawExtension{Raw: raw},
+					UserInfo:  authenticationv1.UserInfo{Username: test.user},
+				},
+			})
+			if response.Allowed != test.allowed {
+				t.Errorf("expected allowed to be %v, got %+v", test.allowed, response.Result)
+			}
+		})
+	}
+}
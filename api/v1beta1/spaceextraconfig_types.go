@@ -0,0 +1,205 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// ExtraRoleBinding describes a RoleBinding to be created inside of the
+// Namespace of a Space
+type ExtraRoleBinding struct {
+	// Name of the RoleBinding
+	Name string `json:"name"`
+
+	// Role or ClusterRole to bind
+	RoleRef rbac.RoleRef `json:"roleRef"`
+
+	// Subjects holding the referenced role
+	// +optional
+	Subjects []rbac.Subject `json:"subjects,omitempty"`
+}
+
+// SpaceExtraConfigSpec defines the desired state of SpaceExtraConfig
+type SpaceExtraConfigSpec struct {
+	// Optional names of the Spaces, defined inside of the same Namespace,
+	// the configuration applies to. The configuration applies to all the
+	// Spaces of the Namespace when empty.
+	// +optional
+	Spaces []string `json:"spaces,omitempty"`
+
+	// Optional map with additional labels to add to the Namespace of the
+	// Space
+	// +optional
+	NamespaceLabels map[string]string `json:"namespaceLabels,omitempty"`
+
+	// Optional map with additional annotations to add to the Namespace of
+	// the Space
+	// +optional
+	NamespaceAnnotations map[string]string `json:"namespaceAnnotations,omitempty"`
+
+	// Optional RoleBindings to create inside of the Namespace of the Space
+	// +optional
+	RoleBindings []ExtraRoleBinding `json:"roleBindings,omitempty"`
+
+	// Optional ResourceQuota to enforce inside of the Namespace of the Space
+	// +optional
+	ResourceQuota *corev1.ResourceQuotaSpec `json:"resourceQuota,omitempty"`
+
+	// Optional LimitRange to enforce inside of the Namespace of the Space
+	// +optional
+	LimitRange *corev1.LimitRangeSpec `json:"limitRange,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SpaceExtraConfig is the Schema for the spaceextraconfigs API. It holds
+// additional configuration applied to the Spaces defined inside of the
+// same Namespace.
+type SpaceExtraConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SpaceExtraConfigSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SpaceExtraConfigList contains a list of SpaceExtraConfig
+type SpaceExtraConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SpaceExtraConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SpaceExtraConfig{}, &SpaceExtraConfigList{})
+}
+
+// AppliesTo returns true when the configuration applies to the Space with
+// the given name
+func (c *SpaceExtraConfig) AppliesTo(spaceName string) bool {
+	if len(c.Spec.Spaces) == 0 {
+		return true
+	}
+	for _, name := range c.Spec.Spaces {
+		if name == spaceName {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeSpaceExtraConfigs merges the SpaceExtraConfig objects applying to the
+// given Space. The objects are processed by name: when two of them define the
+// same label, annotation, RoleBinding or quota the last one wins.
+func MergeSpaceExtraConfigs(extraConfigs []SpaceExtraConfig, spaceName string) *SpaceExtraConfigSpec {
+	sorted := make([]SpaceExtraConfig, len(extraConfigs))
+	copy(sorted, extraConfigs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	merged := &SpaceExtraConfigSpec{
+		NamespaceLabels:      map[string]string{},
+		NamespaceAnnotations: map[string]string{},
+	}
+	roleBindingIndex := map[string]int{}
+
+	for i := range sorted {
+		extraConfig := &sorted[i]
+		if !extraConfig.AppliesTo(spaceName) {
+			continue
+		}
+
+		for key, value := range extraConfig.Spec.NamespaceLabels {
+			merged.NamespaceLabels[key] = value
+		}
+		for key, value := range extraConfig.Spec.NamespaceAnnotations {
+			merged.NamespaceAnnotations[key] = value
+		}
+
+		for _, roleBinding := range extraConfig.Spec.RoleBindings {
+			if common.IsBuiltinRoleBindingName(roleBinding.Name) {
+				continue
+			}
+			if index, found := roleBindingIndex[roleBinding.Name]; found {
+				merged.RoleBindings[index] = *roleBinding.DeepCopy()
+				continue
+			}
+			roleBindingIndex[roleBinding.Name] = len(merged.RoleBindings)
+			merged.RoleBindings = append(merged.RoleBindings, *roleBinding.DeepCopy())
+		}
+
+		if extraConfig.Spec.ResourceQuota != nil {
+			merged.ResourceQuota = MergeResourceQuotaSpecs(merged.ResourceQuota, extraConfig.Spec.ResourceQuota)
+		}
+
+		if extraConfig.Spec.LimitRange != nil {
+			if merged.LimitRange == nil {
+				merged.LimitRange = &corev1.LimitRangeSpec{}
+			}
+			for _, limit := range extraConfig.Spec.LimitRange.Limits {
+				merged.LimitRange.Limits = append(merged.LimitRange.Limits, *limit.DeepCopy())
+			}
+		}
+	}
+
+	return merged
+}
+
+// MergeResourceQuotaSpecs returns a new ResourceQuotaSpec made of base
+// overridden by override. The hard limits are merged key by key, the scopes
+// are joined.
+func MergeResourceQuotaSpecs(base, override *corev1.ResourceQuotaSpec) *corev1.ResourceQuotaSpec {
+	if base == nil {
+		return override.DeepCopy()
+	}
+
+	merged := base.DeepCopy()
+	if len(override.Hard) > 0 && merged.Hard == nil {
+		merged.Hard = corev1.ResourceList{}
+	}
+	for name, quantity := range override.Hard {
+		merged.Hard[name] = quantity.DeepCopy()
+	}
+
+	for _, scope := range override.Scopes {
+		found := false
+		for _, existing := range merged.Scopes {
+			if existing == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged.Scopes = append(merged.Scopes, scope)
+		}
+	}
+
+	if override.ScopeSelector != nil {
+		merged.ScopeSelector = override.ScopeSelector.DeepCopy()
+	}
+
+	return merged
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func extraConfig(name string, spaces []string, spec SpaceExtraConfigSpec) SpaceExtraConfig {
+	spec.Spaces = spaces
+	return SpaceExtraConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "acme-spaces"},
+		Spec:       spec,
+	}
+}
+
+func roleBinding(name, clusterRole string) ExtraRoleBinding {
+	return ExtraRoleBinding{
+		Name: name,
+		RoleRef: rbac.RoleRef{
+			APIGroup: rbac.GroupName,
+			Kind:     "ClusterRole",
+			Name:     clusterRole,
+		},
+	}
+}
+
+func TestMergeSpaceExtraConfigs(t *testing.T) {
+	tests := []struct {
+		name         string
+		extraConfigs []SpaceExtraConfig
+		expected     SpaceExtraConfigSpec
+	}{
+		{
+			name:         "no configuration",
+			extraConfigs: nil,
+			expected: SpaceExtraConfigSpec{
+				NamespaceLabels:      map[string]string{},
+				NamespaceAnnotations: map[string]string{},
+			},
+		},
+		{
+			name: "configuration of other Spaces is ignored",
+			extraConfigs: []SpaceExtraConfig{
+				extraConfig("other", []string{"api"}, SpaceExtraConfigSpec{
+					NamespaceLabels: map[string]string{"tier": "gold"},
+				}),
+			},
+			expected: SpaceExtraConfigSpec{
+				NamespaceLabels:      map[string]string{},
+				NamespaceAnnotations: map[string]string{},
+			},
+		},
+		{
+			name: "later names win",
+			extraConfigs: []SpaceExtraConfig{
+				extraConfig("b", nil, SpaceExtraConfigSpec{
+					NamespaceLabels:      map[string]string{"tier": "silver"},
+					NamespaceAnnotations: map[string]string{"contact": "bob"},
+					RoleBindings:         []ExtraRoleBinding{roleBinding("monitoring", "edit")},
+				}),
+				extraConfig("a", []string{"web"}, SpaceExtraConfigSpec{
+					NamespaceLabels: map[string]string{"tier": "gold", "team": "a"},
+					RoleBindings: []ExtraRoleBinding{
+						roleBinding("monitoring", "view"),
+						roleBinding("ci", "edit"),
+					},
+				}),
+			},
+			expected: SpaceExtraConfigSpec{
+				NamespaceLabels:      map[string]string{"tier": "silver", "team": "a"},
+				NamespaceAnnotations: map[string]string{"contact": "bob"},
+				RoleBindings: []ExtraRoleBinding{
+					roleBinding("monitoring", "edit"),
+					roleBinding("ci", "edit"),
+				},
+			},
+		},
+		{
+			name: "builtin RoleBindings are skipped",
+			extraConfigs: []SpaceExtraConfig{
+				extraConfig("a", nil, SpaceExtraConfigSpec{
+					RoleBindings: []ExtraRoleBinding{
+						roleBinding("administrators", "cluster-admin"),
+						roleBinding("ci", "edit"),
+					},
+				}),
+			},
+			expected: SpaceExtraConfigSpec{
+				NamespaceLabels:      map[string]string{},
+				NamespaceAnnotations: map[string]string{},
+				RoleBindings:         []ExtraRoleBinding{roleBinding("ci", "edit")},
+			},
+		},
+		{
+			name: "quotas are merged and limits are joined",
+			extraConfigs: []SpaceExtraConfig{
+				extraConfig("a", nil, SpaceExtraConfigSpec{
+					ResourceQuota: &corev1.ResourceQuotaSpec{
+						Hard: corev1.ResourceList{
+							corev1.ResourceRequestsCPU:    resource.MustParse("1"),
+							corev1.ResourceRequestsMemory: resource.MustParse("1Gi"),
+						},
+					},
+					LimitRange: &corev1.LimitRangeSpec{
+						Limits: []corev1.LimitRangeItem{{Type: corev1.LimitTypeContainer}},
+					},
+				}),
+				extraConfig("b", []string{"web"}, SpaceExtraConfigSpec{
+					ResourceQuota: &corev1.ResourceQuotaSpec{
+						Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("2")},
+					},
+					LimitRange: &corev1.LimitRangeSpec{
+						Limits: []corev1.LimitRangeItem{{Type: corev1.LimitTypePod}},
+					},
+				}),
+			},
+			expected: SpaceExtraConfigSpec{
+				NamespaceLabels:      map[string]string{},
+				NamespaceAnnotations: map[string]string{},
+				ResourceQuota: &corev1.ResourceQuotaSpec{
+					Hard: corev1.ResourceList{
+						corev1.ResourceRequestsCPU:    resource.MustParse("2"),
+						corev1.ResourceRequestsMemory: resource.MustParse("1Gi"),
+					},
+				},
+				LimitRange: &corev1.LimitRangeSpec{
+					Limits: []corev1.LimitRangeItem{
+						{Type: corev1.LimitTypeContainer},
+						{Type: corev1.LimitTypePod},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged := MergeSpaceExtraConfigs(test.extraConfigs, "web")
+			if !equality.Semantic.DeepEqual(merged, &test.expected) {
+				t.Errorf("unexpected merge:\nexpected %+v\ngot      %+v", test.expected, *merged)
+			}
+		})
+	}
+}
+
+func TestMergeResourceQuotaSpecs(t *testing.T) {
+	terminating := &corev1.ScopeSelector{
+		MatchExpressions: []corev1.ScopedResourceSelectorRequirement{{
+			ScopeName: corev1.ResourceQuotaScopeTerminating,
+			Operator:  corev1.ScopeSelectorOpExists,
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		base     *corev1.ResourceQuotaSpec
+		override *corev1.ResourceQuotaSpec
+		expected *corev1.ResourceQuotaSpec
+	}{
+		{
+			name: "no base",
+			base: nil,
+			override: &corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")},
+			},
+			expected: &corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")},
+			},
+		},
+		{
+			name: "hard limits merged key by key",
+			base: &corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{
+					corev1.ResourcePods:        resource.MustParse("10"),
+					corev1.ResourceRequestsCPU: resource.MustParse("1"),
+				},
+			},
+			override: &corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("4")},
+			},
+			expected: &corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{
+					corev1.ResourcePods:        resource.MustParse("10"),
+					corev1.ResourceRequestsCPU: resource.MustParse("4"),
+				},
+			},
+		},
+		{
+			name: "base without hard limits",
+			base: &corev1.ResourceQuotaSpec{
+				Scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort},
+			},
+			override: &corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("5")},
+			},
+			expected: &corev1.ResourceQuotaSpec{
+				Hard:   corev1.ResourceList{corev1.ResourcePods: resource.MustParse("5")},
+				Scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort},
+			},
+		},
+		{
+			name: "scopes joined without duplicates, selector overridden",
+			base: &corev1.ResourceQuotaSpec{
+				Scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort},
+			},
+			override: &corev1.ResourceQuotaSpec{
+				Scopes: []corev1.ResourceQuotaScope{
+					corev1.ResourceQuotaScopeBestEffort,
+					corev1.ResourceQuotaScopeTerminating,
+				},
+				ScopeSelector: terminating,
+			},
+			expected: &corev1.ResourceQuotaSpec{
+				Scopes: []corev1.ResourceQuotaScope{
+					corev1.ResourceQuotaScopeBestEffort,
+					corev1.ResourceQuotaScopeTerminating,
+				},
+				ScopeSelector: terminating,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var before *corev1.ResourceQuotaSpec
+			if test.base != nil {
+				before = test.base.DeepCopy()
+			}
+
+			merged := MergeResourceQuotaSpecs(test.base, test.override)
+			if !equality.Semantic.DeepEqual(merged, test.expected) {
+				t.Errorf("unexpected merge:\nexpected %+v\ngot      %+v", *test.expected, *merged)
+			}
+			if !equality.Semantic.DeepEqual(test.base, before) {
+				t.Errorf("base has been modified: %+v", *test.base)
+			}
+		})
+	}
+}
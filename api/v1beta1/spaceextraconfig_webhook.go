@@ -0,0 +1,321 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// log is for logging in this package.
+var spaceextraconfiglog = logf.Log.WithName("spaceextraconfig-resource")
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-k8s-suse-com-v1beta1-spaceextraconfig,mutating=false,failurePolicy=fail,groups=k8s.suse.com,resources=spaceextraconfigs,versions=v1beta1,name=vspaceextraconfig.v1beta1.kb.io
+
+// SetupWebhookWithManager registers the validating webhook of
+// SpaceExtraConfig. The webhook needs the user making the request, hence
+// it's not built on top of webhook.Validator.
+func (r *SpaceExtraConfig) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookClient = mgr.GetClient()
+	mgr.GetWebhookServer().Register("/validate-k8s-suse-com-v1beta1-spaceextraconfig",
+		&webhook.Admission{Handler: &requestValidatingHandler{object: r}})
+	return nil
+}
+
+var _ requestValidator = &SpaceExtraConfig{}
+
+// validateRequest implements requestValidator
+func (r *SpaceExtraConfig) validateRequest(ctx context.Context, user authenticationv1.UserInfo, old runtime.Object) error {
+	spaceextraconfiglog.Info("Validating SpaceExtraConfig object",
+		"Namespace", r.Namespace,
+		"Name", r.Name,
+		"User", user.Username)
+	if r.GetDeletionTimestamp() != nil {
+		return nil
+	}
+	oldConfig, _ := old.(*SpaceExtraConfig)
+
+	specPath := field.NewPath("spec")
+	allErrs := validateMemberList(r.Spec.Spaces, specPath.Child("spaces"))
+	allErrs = append(allErrs, validateLabels(r.Spec.NamespaceLabels, specPath.Child("namespaceLabels"))...)
+	allErrs = append(allErrs, validateAnnotations(r.Spec.NamespaceAnnotations, specPath.Child("namespaceAnnotations"))...)
+	allErrs = append(allErrs, r.validateRoleBindings()...)
+	if r.Spec.LimitRange != nil {
+		allErrs = append(allErrs, validateLimitRange(r.Spec.LimitRange, specPath.Child("limitRange"))...)
+	}
+
+	quotaChanged := oldConfig == nil ||
+		!equality.Semantic.DeepEqual(oldConfig.Spec.ResourceQuota, r.Spec.ResourceQuota) ||
+		!equality.Semantic.DeepEqual(oldConfig.Spec.Spaces, r.Spec.Spaces)
+	if len(allErrs) == 0 && webhookClient != nil && quotaChanged {
+		budgetErrs, err := r.validateBudget(ctx)
+		if err != nil {
+			return err
+		}
+		allErrs = append(allErrs, budgetErrs...)
+	}
+
+	if len(allErrs) == 0 && webhookClient != nil {
+		authorizationErrs, err := r.authorizeRoleBindings(ctx, user, oldConfig)
+		if err != nil {
+			return err
+		}
+		allErrs = append(allErrs, authorizationErrs...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("SpaceExtraConfig").GroupKind(), r.Name, allErrs)
+}
+
+// validateBudget ensures the quota of the SpaceExtraConfig doesn't push the
+// resources allocated by the Spaces of the Organization over the
+// Organization budget. Changes that don't increase the allocated resources
+// are always allowed.
+func (r *SpaceExtraConfig) validateBudget(ctx context.Context) (field.ErrorList, error) {
+	allErrs := field.ErrorList{}
+
+	organizationName, found, err := common.OrganizationOfSpacesNamespace(webhookClient, r.Namespace, ctx)
+	if err != nil || !found {
+		return allErrs, err
+	}
+	organization := &Organization{}
+	if err := webhookClient.Get(ctx, client.ObjectKey{Name: organizationName}, organization); err != nil {
+		if apierrors.IsNotFound(err) {
+			return allErrs, nil
+		}
+		return allErrs, err
+	}
+	if len(organization.Spec.Budget) == 0 {
+		return allErrs, nil
+	}
+
+	spaces := &SpaceList{}
+	if err := webhookClient.List(ctx, spaces, client.InNamespace(r.Namespace)); err != nil {
+		return allErrs, err
+	}
+	extraConfigs := &SpaceExtraConfigList{}
+	if err := webhookClient.List(ctx, extraConfigs, client.InNamespace(r.Namespace)); err != nil {
+		return allErrs, err
+	}
+	updated := []SpaceExtraConfig{*r}
+	for _, extraConfig := range extraConfigs.Items {
+		if extraConfig.Name != r.Name {
+			updated = append(updated, extraConfig)
+		}
+	}
+
+	before := AllocatedBudget(organization.Spec.Budget, spaces.Items, extraConfigs.Items)
+	after := AllocatedBudget(organization.Spec.Budget, spaces.Items, updated)
+	hardPath := field.NewPath("spec", "resourceQuota", "hard")
+	for name, budget := range organization.Spec.Budget {
+		allocated := after[name]
+		previous := before[name]
+		if allocated.Cmp(budget) > 0 && allocated.Cmp(previous) > 0 {
+			allErrs = append(allErrs, field.Forbidden(hardPath.Key(string(name)),
+				fmt.Sprintf("exceeds the budget of Organization %s: the Spaces would allocate %s, the budget is %s",
+					organizationName, allocated.String(), budget.String())))
+		}
+	}
+
+	return allErrs, nil
+}
+
+// validateRoleBindings checks the RoleBindings to create inside of the
+// Namespace of the Spaces
+func (r *SpaceExtraConfig) validateRoleBindings() field.ErrorList {
+	allErrs := field.ErrorList{}
+	roleBindingNames := map[string]bool{}
+	for i, roleBinding := range r.Spec.RoleBindings {
+		path := field.NewPath("spec", "roleBindings").Index(i)
+		switch {
+		case roleBinding.Name == "":
+			allErrs = append(allErrs, field.Required(path.Child("name"), "must not be empty"))
+		case common.IsBuiltinRoleBindingName(roleBinding.Name):
+			allErrs = append(allErrs, field.Forbidden(path.Child("name"),
+				fmt.Sprintf("%s is reserved by the operator", roleBinding.Name)))
+		case roleBindingNames[roleBinding.Name]:
+			allErrs = append(allErrs, field.Duplicate(path.Child("name"), roleBinding.Name))
+		default:
+			for _, msg := range validation.IsDNS1123Subdomain(roleBinding.Name) {
+				allErrs = append(allErrs, field.Invalid(path.Child("name"), roleBinding.Name, msg))
+			}
+		}
+		roleBindingNames[roleBinding.Name] = true
+
+		roleRefPath := path.Child("roleRef")
+		if roleBinding.RoleRef.APIGroup != rbac.GroupName {
+			allErrs = append(allErrs, field.NotSupported(roleRefPath.Child("apiGroup"),
+				roleBinding.RoleRef.APIGroup, []string{rbac.GroupName}))
+		}
+		if roleBinding.RoleRef.Kind != "ClusterRole" && roleBinding.RoleRef.Kind != "Role" {
+			allErrs = append(allErrs, field.NotSupported(roleRefPath.Child("kind"),
+				roleBinding.RoleRef.Kind, []string{"ClusterRole", "Role"}))
+		}
+		if roleBinding.RoleRef.Name == "" {
+			allErrs = append(allErrs, field.Required(roleRefPath.Child("name"), "must not be empty"))
+		}
+
+		for j, subject := range roleBinding.Subjects {
+			allErrs = append(allErrs, validateSubject(subject, path.Child("subjects").Index(j))...)
+		}
+	}
+	return allErrs
+}
+
+// authorizeRoleBindings ensures the user is allowed to bind the roles
+// referenced by the RoleBindings. The operator creates the RoleBindings on
+// behalf of the user, without this check anybody able to write a
+// SpaceExtraConfig could grant any ClusterRole. The bind permission is
+// checked inside of the Namespace of the SpaceExtraConfig. RoleBindings not
+// changed by an update are not checked again.
+func (r *SpaceExtraConfig) authorizeRoleBindings(ctx context.Context, user authenticationv1.UserInfo, old *SpaceExtraConfig) (field.ErrorList, error) {
+	allErrs := field.ErrorList{}
+	for i, roleBinding := range r.Spec.RoleBindings {
+		if old != nil && oldRoleBindingUnchanged(old, roleBinding) {
+			continue
+		}
+
+		resource := "clusterroles"
+		if roleBinding.RoleRef.Kind == "Role" {
+			resource = "roles"
+		}
+		allowed, err := userCan(ctx, user, authorizationv1.ResourceAttributes{
+			Namespace: r.Namespace,
+			Verb:      "bind",
+			Group:     rbac.GroupName,
+			Resource:  resource,
+			Name:      roleBinding.RoleRef.Name,
+		})
+		if err != nil {
+			return allErrs, err
+		}
+		if !allowed {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "roleBindings").Index(i).Child("roleRef"),
+				fmt.Sprintf("user %s cannot bind %s %s inside of the Namespace %s",
+					user.Username, roleBinding.RoleRef.Kind, roleBinding.RoleRef.Name, r.Namespace)))
+		}
+	}
+	return allErrs, nil
+}
+
+// oldRoleBindingUnchanged returns true when the old SpaceExtraConfig already
+// defines the same RoleBinding
+func oldRoleBindingUnchanged(old *SpaceExtraConfig, roleBinding ExtraRoleBinding) bool {
+	for _, oldRoleBinding := range old.Spec.RoleBindings {
+		if equality.Semantic.DeepEqual(oldRoleBinding, roleBinding) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSubject checks the subject of a RoleBinding
+func validateSubject(subject rbac.Subject, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if subject.Name == "" {
+		allErrs = append(allErrs, field.Required(path.Child("name"), "must not be empty"))
+	}
+	switch subject.Kind {
+	case rbac.UserKind, rbac.GroupKind:
+		if subject.APIGroup != rbac.GroupName {
+			allErrs = append(allErrs, field.NotSupported(path.Child("apiGroup"),
+				subject.APIGroup, []string{rbac.GroupName}))
+		}
+	case rbac.ServiceAccountKind:
+		if subject.APIGroup != "" {
+			allErrs = append(allErrs, field.NotSupported(path.Child("apiGroup"),
+				subject.APIGroup, []string{""}))
+		}
+		if subject.Namespace == "" {
+			allErrs = append(allErrs, field.Required(path.Child("namespace"), "must not be empty"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("kind"),
+			subject.Kind, []string{rbac.UserKind, rbac.GroupKind, rbac.ServiceAccountKind}))
+	}
+	return allErrs
+}
+
+// validateLimitRange checks the limits of a LimitRange are consistent:
+// min <= defaultRequest <= default <= max for every resource
+func validateLimitRange(limitRange *corev1.LimitRangeSpec, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, limit := range limitRange.Limits {
+		limitPath := path.Child("limits").Index(i)
+		switch limit.Type {
+		case corev1.LimitTypeContainer:
+		case corev1.LimitTypePod, corev1.LimitTypePersistentVolumeClaim:
+			if len(limit.Default) > 0 {
+				allErrs = append(allErrs, field.Forbidden(limitPath.Child("default"),
+					fmt.Sprintf("not supported when type is %s", limit.Type)))
+			}
+			if len(limit.DefaultRequest) > 0 {
+				allErrs = append(allErrs, field.Forbidden(limitPath.Child("defaultRequest"),
+					fmt.Sprintf("not supported when type is %s", limit.Type)))
+			}
+		default:
+			allErrs = append(allErrs, field.NotSupported(limitPath.Child("type"), limit.Type,
+				[]string{
+					string(corev1.LimitTypeContainer),
+					string(corev1.LimitTypePod),
+					string(corev1.LimitTypePersistentVolumeClaim)}))
+		}
+
+		lists := []struct {
+			name string
+			list corev1.ResourceList
+		}{
+			{"min", limit.Min},
+			{"defaultRequest", limit.DefaultRequest},
+			{"default", limit.Default},
+			{"max", limit.Max},
+		}
+		for j, lower := range lists {
+			for name, quantity := range lower.list {
+				if quantity.Sign() < 0 {
+					allErrs = append(allErrs, field.Invalid(limitPath.Child(lower.name).Key(string(name)),
+						quantity.String(), "must not be negative"))
+				}
+				for _, upper := range lists[j+1:] {
+					if bound, found := upper.list[name]; found && quantity.Cmp(bound) > 0 {
+						allErrs = append(allErrs, field.Invalid(limitPath.Child(lower.name).Key(string(name)),
+							quantity.String(), fmt.Sprintf("must not be greater than %s %s", upper.name, bound.String())))
+					}
+				}
+			}
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidateRoleBindings(t *testing.T) {
+	user := rbac.Subject{Kind: rbac.UserKind, APIGroup: rbac.GroupName, Name: "alice"}
+
+	tests := []struct {
+		name         string
+		roleBindings []ExtraRoleBinding
+		errField     string
+	}{
+		{"valid", []ExtraRoleBinding{roleBinding("ci", "edit")}, ""},
+		{"builtin name", []ExtraRoleBinding{roleBinding("administrators", "edit")}, "spec.roleBindings[0].name"},
+		{"duplicated name", []ExtraRoleBinding{roleBinding("ci", "edit"), roleBinding("ci", "view")}, "spec.roleBindings[1].name"},
+		{
+			"unknown kind",
+			[]ExtraRoleBinding{{Name: "ci", RoleRef: rbac.RoleRef{APIGroup: rbac.GroupName, Kind: "Group", Name: "edit"}}},
+			"spec.roleBindings[0].roleRef.kind",
+		},
+		{
+			"wrong API group",
+			[]ExtraRoleBinding{{Name: "ci", RoleRef: rbac.RoleRef{APIGroup: "example.com", Kind: "ClusterRole", Name: "edit"}}},
+			"spec.roleBindings[0].roleRef.apiGroup",
+		},
+		{
+			"ServiceAccount without namespace",
+			[]ExtraRoleBinding{{
+				Name:     "ci",
+				RoleRef:  roleBinding("ci", "edit").RoleRef,
+				Subjects: []rbac.Subject{user, {Kind: rbac.ServiceAccountKind, Name: "deployer"}},
+			}},
+			"spec.roleBindings[0].subjects[1].namespace",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := &SpaceExtraConfig{Spec: SpaceExtraConfigSpec{RoleBindings: test.roleBindings}}
+			assertFieldError(t, config.validateRoleBindings(), test.errField)
+		})
+	}
+}
+
+func TestValidateLimitRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    corev1.LimitRangeItem
+		errField string
+	}{
+		{
+			name: "valid",
+			limit: corev1.LimitRangeItem{
+				Type:           corev1.LimitTypeContainer,
+				Min:            corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+				DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")},
+				Default:        corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				Max:            corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+			},
+		},
+		{
+			name:     "unknown type",
+			limit:    corev1.LimitRangeItem{Type: "Node"},
+			errField: "spec.limitRange.limits[0].type",
+		},
+		{
+			name: "default on pods",
+			limit: corev1.LimitRangeItem{
+				Type:    corev1.LimitTypePod,
+				Default: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+			},
+			errField: "spec.limitRange.limits[0].default",
+		},
+		{
+			name: "negative value",
+			limit: corev1.LimitRangeItem{
+				Type: corev1.LimitTypeContainer,
+				Min:  corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("-1")},
+			},
+			errField: "spec.limitRange.limits[0].min[cpu]",
+		},
+		{
+			name: "default greater than max",
+			limit: corev1.LimitRangeItem{
+				Type:    corev1.LimitTypeContainer,
+				Default: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+				Max:     corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+			},
+			errField: "spec.limitRange.limits[0].default[memory]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limitRange := &corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{test.limit}}
+			assertFieldError(t, validateLimitRange(limitRange, field.NewPath("spec", "limitRange")), test.errField)
+		})
+	}
+}
+
+// assertFieldError checks allErrs holds only an error about errField, or no
+// errors at all when errField is empty
+func assertFieldError(t *testing.T, allErrs field.ErrorList, errField string) {
+	t.Helper()
+	if errField == "" {
+		if len(allErrs) > 0 {
+			t.Errorf("expected no errors, got %v", allErrs)
+		}
+		return
+	}
+	if len(allErrs) != 1 || allErrs[0].Field != errField {
+		t.Errorf("expected an error about %s, got %v", errField, allErrs)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SpaceMoveSpec defines the desired state of SpaceMove
+type SpaceMoveSpec struct {
+	// Name of the Space to move, defined inside of the same Namespace of
+	// the SpaceMove
+	Space string `json:"space"`
+
+	// Name of the Organization the Space is moved to
+	TargetOrganization string `json:"targetOrganization"`
+}
+
+// SpaceMovePhase is a label for the condition of a SpaceMove at the current
+// time
+type SpaceMovePhase string
+
+const (
+	// SpaceMovePhasePending means the move has not started yet
+	SpaceMovePhasePending SpaceMovePhase = "Pending"
+	// SpaceMovePhaseMoving means the Space is being moved
+	SpaceMovePhaseMoving SpaceMovePhase = "Moving"
+	// SpaceMovePhaseCompleted means the Space has been moved to the target
+	// Organization
+	SpaceMovePhaseCompleted SpaceMovePhase = "Completed"
+	// SpaceMovePhaseFailed means the Space cannot be moved, nothing has
+	// been changed
+	SpaceMovePhaseFailed SpaceMovePhase = "Failed"
+)
+
+// SpaceMoveStatus defines the observed state of SpaceMove
+type SpaceMoveStatus struct {
+	// Current phase of the move
+	// +optional
+	Phase SpaceMovePhase `json:"phase,omitempty"`
+
+	// Human readable description of the current phase
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Name of the Namespace of the Space being moved
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Space",type="string",JSONPath=".spec.space"
+// +kubebuilder:printcolumn:name="Target",type="string",JSONPath=".spec.targetOrganization"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// SpaceMove is the Schema for the spacemoves API. It requests to move a
+// Space, defined inside of the same Namespace, to another Organization
+// without deleting the Namespace of the Space.
+type SpaceMove struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SpaceMoveSpec   `json:"spec,omitempty"`
+	Status SpaceMoveStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SpaceMoveList contains a list of SpaceMove
+type SpaceMoveList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SpaceMove `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SpaceMove{}, &SpaceMoveList{})
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// log is for logging in this package.
+var spacemovelog = logf.Log.WithName("spacemove-resource")
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-k8s-suse-com-v1beta1-spacemove,mutating=false,failurePolicy=fail,groups=k8s.suse.com,resources=spacemoves,versions=v1beta1,name=vspacemove.v1beta1.kb.io
+
+// SetupWebhookWithManager registers the validating webhook of SpaceMove. The
+// webhook needs the user making the request, hence it's not built on top of
+// webhook.Validator.
+func (r *SpaceMove) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	webhookClient = mgr.GetClient()
+	mgr.GetWebhookServer().Register("/validate-k8s-suse-com-v1beta1-spacemove",
+		&webhook.Admission{Handler: &requestValidatingHandler{object: r}})
+	return nil
+}
+
+var _ requestValidator = &SpaceMove{}
+
+// validateRequest implements requestValidator. The Space is moved by the
+// operator, the user must be allowed to delete it from the source
+// Organization and to create it inside of the target one.
+func (r *SpaceMove) validateRequest(ctx context.Context, user authenticationv1.UserInfo, old runtime.Object) error {
+	spacemovelog.Info("Validating SpaceMove object",
+		"Namespace", r.Namespace,
+		"Name", r.Name,
+		"User", user.Username)
+	if r.GetDeletionTimestamp() != nil {
+		return nil
+	}
+
+	specPath := field.NewPath("spec")
+	allErrs := field.ErrorList{}
+	if oldMove, ok := old.(*SpaceMove); ok {
+		if !equality.Semantic.DeepEqual(oldMove.Spec, r.Spec) {
+			allErrs = append(allErrs, field.Forbidden(specPath, "cannot be changed"))
+		}
+		return r.invalid(allErrs)
+	}
+
+	if r.Spec.Space == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("space"), "must not be empty"))
+	}
+	if r.Spec.TargetOrganization == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("targetOrganization"), "must not be empty"))
+	} else {
+		for _, msg := range validation.IsDNS1123Label(r.Spec.TargetOrganization) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("targetOrganization"), r.Spec.TargetOrganization, msg))
+		}
+	}
+	if len(allErrs) > 0 || webhookClient == nil {
+		return r.invalid(allErrs)
+	}
+
+	sourceOrganization, found, err := common.OrganizationOfSpacesNamespace(webhookClient, r.Namespace, ctx)
+	if err != nil {
+		return err
+	}
+	if !found {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("metadata", "namespace"), r.Namespace,
+			"SpaceMoves must be created inside of the Namespace holding the Spaces of an Organization"))
+		return r.invalid(allErrs)
+	}
+	if sourceOrganization == r.Spec.TargetOrganization {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("targetOrganization"), r.Spec.TargetOrganization,
+			fmt.Sprintf("Space %s already belongs to Organization %s", r.Spec.Space, sourceOrganization)))
+		return r.invalid(allErrs)
+	}
+
+	checks := []struct {
+		organization string
+		attributes   authorizationv1.ResourceAttributes
+	}{
+		{
+			organization: sourceOrganization,
+			attributes: authorizationv1.ResourceAttributes{
+				Namespace: r.Namespace,
+				Verb:      "delete",
+				Group:     GroupVersion.Group,
+				Resource:  "spaces",
+				Name:      r.Spec.Space,
+			},
+		},
+		{
+			organization: r.Spec.TargetOrganization,
+			attributes: authorizationv1.ResourceAttributes{
+				Namespace: common.ComputeSpacesNamespaceFromOrganizationName(r.Spec.TargetOrganization),
+				Verb:      "create",
+				Group:     GroupVersion.Group,
+				Resource:  "spaces",
+			},
+		},
+	}
+	for _, check := range checks {
+		allowed, err := userCan(ctx, user, check.attributes)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			allErrs = append(allErrs, field.Forbidden(specPath,
+				fmt.Sprintf("user %s cannot %s Spaces of Organization %s",
+					user.Username, check.attributes.Verb, check.organization)))
+		}
+	}
+
+	return r.invalid(allErrs)
+}
+
+// invalid returns an Invalid error made of the given errors, nil when there
+// are no errors
+func (r *SpaceMove) invalid(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("SpaceMove").GroupKind(), r.Name, allErrs)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// validateMemberList checks the given list of users or groups doesn't have
+// empty or duplicated entries
+func validateMemberList(members []string, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	seen := map[string]bool{}
+	for i, member := range members {
+		if strings.TrimSpace(member) == "" {
+			allErrs = append(allErrs, field.Required(path.Index(i), "must not be empty"))
+			continue
+		}
+		if seen[member] {
+			allErrs = append(allErrs, field.Duplicate(path.Index(i), member))
+		}
+		seen[member] = true
+	}
+	return allErrs
+}
+
+// validateServiceAccounts checks the given ServiceAccounts are expressed in
+// the "namespace/name" format. The namespace can be omitted only when
+// requireNamespace is false.
+func validateServiceAccounts(serviceAccounts []string, requireNamespace bool, path *field.Path) field.ErrorList {
+	allErrs := validateMemberList(serviceAccounts, path)
+	for i, serviceAccount := range serviceAccounts {
+		if serviceAccount == "" {
+			continue
+		}
+		name := serviceAccount
+		if parts := strings.SplitN(serviceAccount, "/", 2); len(parts) == 2 {
+			for _, msg := range validation.IsDNS1123Label(parts[0]) {
+				allErrs = append(allErrs, field.Invalid(path.Index(i), serviceAccount,
+					"invalid namespace: "+msg))
+			}
+			name = parts[1]
+		} else if requireNamespace {
+			allErrs = append(allErrs, field.Invalid(path.Index(i), serviceAccount,
+				"must be in the namespace/name format"))
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			allErrs = append(allErrs, field.Invalid(path.Index(i), serviceAccount,
+				"invalid ServiceAccount name: "+msg))
+		}
+	}
+	return allErrs
+}
+
+// validateLabelValueName checks the name of an object can be used as the
+// value of the labels identifying the objects created on its behalf
+func validateLabelValueName(name string, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for _, msg := range validation.IsValidLabelValue(name) {
+		allErrs = append(allErrs, field.Invalid(path, name,
+			"cannot be used as the value of the labels set by the operator: "+msg))
+	}
+	return allErrs
+}
+
+// validateLabels checks the keys and the values of the given labels
+func validateLabels(labels map[string]string, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for key, value := range labels {
+		if isReservedKey(key) {
+			allErrs = append(allErrs, field.Forbidden(path.Key(key), "is reserved by the operator"))
+		}
+		for _, msg := range validation.IsQualifiedName(key) {
+			allErrs = append(allErrs, field.Invalid(path, key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			allErrs = append(allErrs, field.Invalid(path.Key(key), value, msg))
+		}
+	}
+	return allErrs
+}
+
+// validateAnnotations checks the keys of the given annotations
+func validateAnnotations(annotations map[string]string, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for key := range annotations {
+		if isReservedKey(key) {
+			allErrs = append(allErrs, field.Forbidden(path.Key(key), "is reserved by the operator"))
+		}
+		for _, msg := range validation.IsQualifiedName(strings.ToLower(key)) {
+			allErrs = append(allErrs, field.Invalid(path, key, msg))
+		}
+	}
+	return allErrs
+}
+
+// isReservedKey returns true when the label or annotation key is used by the
+// operator
+func isReservedKey(key string) bool {
+	return strings.HasPrefix(strings.ToLower(key), common.ReservedKeyPrefix)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidateServiceAccounts(t *testing.T) {
+	path := field.NewPath("spec", "editorServiceAccounts")
+
+	tests := []struct {
+		name             string
+		serviceAccounts  []string
+		requireNamespace bool
+		errField         string
+	}{
+		{"namespace and name", []string{"ci/deployer"}, true, ""},
+		{"name only", []string{"deployer"}, false, ""},
+		{"namespace required", []string{"deployer"}, true, "spec.editorServiceAccounts[0]"},
+		{"invalid namespace", []string{"CI/deployer"}, false, "spec.editorServiceAccounts[0]"},
+		{"invalid name", []string{"ci/Deployer"}, false, "spec.editorServiceAccounts[0]"},
+		{"missing name", []string{"ci/"}, false, "spec.editorServiceAccounts[0]"},
+		{"empty", []string{"ci/deployer", ""}, false, "spec.editorServiceAccounts[1]"},
+		{"duplicated", []string{"ci/deployer", "ci/deployer"}, false, "spec.editorServiceAccounts[1]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allErrs := validateServiceAccounts(test.serviceAccounts, test.requireNamespace, path)
+			assertFieldError(t, allErrs, test.errField)
+		})
+	}
+}
+
+func TestValidateMemberServiceAccounts(t *testing.T) {
+	// The ServiceAccounts of an Organization are granted access to
+	// Namespaces with different names, their namespace is required
+	organization := &Organization{}
+	organization.Spec.EditorServiceAccounts = []string{"deployer"}
+	assertFieldError(t, organization.validateMembers(), "spec.editorServiceAccounts[0]")
+
+	space := &Space{}
+	space.Spec.EditorServiceAccounts = []string{"deployer"}
+	space.Spec.RoleBindings = []SpaceRoleBinding{
+		{Name: "deployer", ClusterRole: "deployer", ServiceAccounts: []string{"ci/Deployer"}},
+	}
+	assertFieldError(t, space.validateMembers(), "spec.roleBindings[0].serviceAccounts[0]")
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// Reasons of the Events recorded on Organization, Space and SpaceMove objects
+const (
+	eventReasonCreated             = "Created"
+	eventReasonUpdated             = "Updated"
+	eventReasonDriftCorrected      = "DriftCorrected"
+	eventReasonDeleted             = "Deleted"
+	eventReasonOrganizationMissing = "OrganizationMissing"
+	eventReasonReconcileFailed     = "ReconcileFailed"
+	eventReasonDeletionBlocked     = "DeletionBlocked"
+	eventReasonNamespaceRetained   = "NamespaceRetained"
+	eventReasonNamespaceOrphaned   = "NamespaceOrphaned"
+	eventReasonNamespaceAdopted    = "NamespaceAdopted"
+	eventReasonMoved               = "Moved"
+	eventReasonMoveFailed          = "MoveFailed"
+)
+
+// recordOperation records an Event on object describing what has been done
+// to one of the resources managed on its behalf and updates the metrics of
+// the Organization. Nothing is recorded when the resource has not been
+// changed.
+func recordOperation(
+	recorder record.EventRecorder,
+	object runtime.Object,
+	organizationName, kind, namespace, name string,
+	result controllerutil.OperationResult) {
+	observeOperation(organizationName, kind, result)
+
+	if namespace != "" {
+		name = namespace + "/" + name
+	}
+
+	switch result {
+	case controllerutil.OperationResultCreated:
+		recorder.Eventf(object, corev1.EventTypeNormal, eventReasonCreated,
+			"Created %s %s", kind, name)
+	case controllerutil.OperationResultUpdated:
+		recorder.Eventf(object, corev1.EventTypeNormal, eventReasonUpdated,
+			"Updated %s %s to match the desired state", kind, name)
+	case common.OperationResultDriftCorrected:
+		recorder.Eventf(object, corev1.EventTypeWarning, eventReasonDriftCorrected,
+			"Reverted changes made by someone else to %s %s", kind, name)
+	}
+}
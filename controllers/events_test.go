@@ -0,0 +1,142 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+func TestRecordOperation(t *testing.T) {
+	tests := []struct {
+		result   controllerutil.OperationResult
+		expected []string
+	}{
+		{controllerutil.OperationResultNone, []string{}},
+		{controllerutil.OperationResultCreated, []string{
+			"Normal Created Created RoleBinding acme-web-space/viewers",
+		}},
+		{controllerutil.OperationResultUpdated, []string{
+			"Normal Updated Updated RoleBinding acme-web-space/viewers to match the desired state",
+		}},
+		{common.OperationResultDriftCorrected, []string{
+			"Warning DriftCorrected Reverted changes made by someone else to RoleBinding acme-web-space/viewers",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.result), func(t *testing.T) {
+			c := &fakeCluster{recorder: record.NewFakeRecorder(10)}
+			recordOperation(c.recorder, newSpace("acme", "web"), "acme", "RoleBinding",
+				"acme-web-space", "viewers", test.result)
+			if events := c.events(); !reflect.DeepEqual(events, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, events)
+			}
+		})
+	}
+}
+
+// assertEvent fails the test when none of the events starts with prefix
+func assertEvent(t *testing.T, events []string, prefix string) {
+	t.Helper()
+	for _, event := range events {
+		if strings.HasPrefix(event, prefix) {
+			return
+		}
+	}
+	t.Errorf("expected an event starting with %q, got %v", prefix, events)
+}
+
+func TestSpaceEvents(t *testing.T) {
+	c := newOrganizationCluster(t, newOrganization("acme"), newSpace("acme", "web"))
+
+	c.reconcileSpace(t, "acme", "web")
+	events := c.events()
+	assertEvent(t, events, "Normal Created Created Namespace acme-web-space")
+	assertEvent(t, events, "Normal Created Created RoleBinding acme-web-space/administrators")
+
+	// Nothing happens when everything is in place
+	c.reconcileSpace(t, "acme", "web")
+	if events = c.events(); len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+
+	roleBinding := &rbac.RoleBinding{}
+	c.get(t, "acme-web-space", "administrators", roleBinding)
+	roleBinding.Subjects = append(roleBinding.Subjects, rbac.Subject{
+		Kind:     rbac.UserKind,
+		Name:     "mallory",
+		APIGroup: rbac.GroupName,
+	})
+	if err := c.Update(context.Background(), roleBinding); err != nil {
+		t.Fatal(err)
+	}
+	c.reconcileSpace(t, "acme", "web")
+	expected := []string{
+		"Warning DriftCorrected Reverted changes made by someone else to RoleBinding acme-web-space/administrators",
+	}
+	if events = c.events(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+}
+
+func TestSpaceFailureEvents(t *testing.T) {
+	organization := newOrganization("acme")
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme-web-space"}}
+	c := newOrganizationCluster(t, organization, newSpace("acme", "web"), namespace)
+
+	_, err := c.spaceReconciler().Reconcile(ctrl.Request{
+		NamespacedName: client.ObjectKey{Name: "web", Namespace: "acme-spaces"},
+	})
+	if err == nil {
+		t.Fatal("expected the reconciliation to fail")
+	}
+	assertEvent(t, c.events(), "Warning ReconcileFailed Namespace acme-web-space already exists")
+
+	if err = c.Delete(context.Background(), organization); err != nil {
+		t.Fatal(err)
+	}
+	c.reconcileSpace(t, "acme", "web")
+	assertEvent(t, c.events(), "Warning OrganizationMissing Cannot find organization acme owning the Space")
+}
+
+func TestOrganizationEvents(t *testing.T) {
+	c := newFakeCluster(newOrganization("acme"))
+
+	c.reconcileOrganization(t, "acme")
+	events := c.events()
+	assertEvent(t, events, "Normal Created Created Namespace acme-spaces")
+	assertEvent(t, events, "Normal Created Created Role acme-spaces/scope-reader")
+	assertEvent(t, events, "Normal Created Created RoleBinding acme-spaces/scope-admin")
+
+	c.reconcileOrganization(t, "acme")
+	if events = c.events(); len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	k8sv1alpha1 "github.com/flavio/organization-operator/api/v1alpha1"
+	k8sv1beta1 "github.com/flavio/organization-operator/api/v1beta1"
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// The tests below don't need a real API server: the reconcilers run against
+// the fake client of controller-runtime. Mind that the fake client deletes
+// the objects right away, ignoring finalizers and owner references.
+
+func init() {
+	utilruntime.Must(k8sv1alpha1.AddToScheme(scheme.Scheme))
+	utilruntime.Must(k8sv1beta1.AddToScheme(scheme.Scheme))
+}
+
+// fakeCluster holds the objects seen by the reconcilers and the Events they
+// record
+type fakeCluster struct {
+	client.Client
+	recorder *record.FakeRecorder
+}
+
+func newFakeCluster(objects ...runtime.Object) *fakeCluster {
+	return &fakeCluster{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, objects...),
+		recorder: record.NewFakeRecorder(100),
+	}
+}
+
+func (c *fakeCluster) organizationReconciler() *OrganizationReconciler {
+	return &OrganizationReconciler{
+		Client:   c,
+		Log:      logf.NullLogger{},
+		Scheme:   scheme.Scheme,
+		Recorder: c.recorder,
+	}
+}
+
+func (c *fakeCluster) spaceReconciler() *SpaceReconciler {
+	return &SpaceReconciler{
+		Client:   c,
+		Log:      logf.NullLogger{},
+		Scheme:   scheme.Scheme,
+		Recorder: c.recorder,
+		ClusterRoles: k8sv1beta1.ClusterRoleMapping{
+			Admin: "admin",
+			Edit:  "edit",
+			View:  "view",
+		},
+	}
+}
+
+func (c *fakeCluster) spaceMoveReconciler() *SpaceMoveReconciler {
+	return &SpaceMoveReconciler{
+		Client:   c,
+		Log:      logf.NullLogger{},
+		Scheme:   scheme.Scheme,
+		Recorder: c.recorder,
+	}
+}
+
+// reconcileOrganization runs the Organization reconciler, failing the test
+// on errors
+func (c *fakeCluster) reconcileOrganization(t *testing.T, name string) ctrl.Result {
+	t.Helper()
+	result, err := c.organizationReconciler().Reconcile(ctrl.Request{
+		NamespacedName: client.ObjectKey{Name: name},
+	})
+	if err != nil {
+		t.Fatalf("cannot reconcile Organization %s: %v", name, err)
+	}
+	return result
+}
+
+// reconcileSpace runs the Space reconciler, failing the test on errors
+func (c *fakeCluster) reconcileSpace(t *testing.T, organizationName, name string) ctrl.Result {
+	t.Helper()
+	result, err := c.spaceReconciler().Reconcile(ctrl.Request{
+		NamespacedName: client.ObjectKey{
+			Name:      name,
+			Namespace: common.ComputeSpacesNamespaceFromOrganizationName(organizationName),
+		},
+	})
+	if err != nil {
+		t.Fatalf("cannot reconcile Space %s: %v", name, err)
+	}
+	return result
+}
+
+// reconcileSpaceMove runs the SpaceMove reconciler, failing the test on
+// errors
+func (c *fakeCluster) reconcileSpaceMove(t *testing.T, organizationName, name string) ctrl.Result {
+	t.Helper()
+	result, err := c.spaceMoveReconciler().Reconcile(ctrl.Request{
+		NamespacedName: client.ObjectKey{
+			Name:      name,
+			Namespace: common.ComputeSpacesNamespaceFromOrganizationName(organizationName),
+		},
+	})
+	if err != nil {
+		t.Fatalf("cannot reconcile SpaceMove %s: %v", name, err)
+	}
+	return result
+}
+
+// get fetches the object, failing the test when it doesn't exist
+func (c *fakeCluster) get(t *testing.T, namespace, name string, obj runtime.Object) {
+	t.Helper()
+	err := c.Get(context.Background(), client.ObjectKey{Name: name, Namespace: namespace}, obj)
+	if err != nil {
+		t.Fatalf("cannot get %T %s/%s: %v", obj, namespace, name, err)
+	}
+}
+
+// events returns the Events recorded so far
+func (c *fakeCluster) events() []string {
+	events := []string{}
+	for {
+		select {
+		case event := <-c.recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func newOrganization(name string) *k8sv1beta1.Organization {
+	return &k8sv1beta1.Organization{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+}
+
+func newSpace(organizationName, name string) *k8sv1beta1.Space {
+	return &k8sv1beta1.Space{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  common.ComputeSpacesNamespaceFromOrganizationName(organizationName),
+			Finalizers: []string{common.SpaceFinalizer},
+		},
+	}
+}
+
+// newOrganizationCluster returns a cluster holding the given objects, where
+// the Organization has already been reconciled
+func newOrganizationCluster(t *testing.T, organization *k8sv1beta1.Organization, objects ...runtime.Object) *fakeCluster {
+	t.Helper()
+	c := newFakeCluster(append([]runtime.Object{organization}, objects...)...)
+	c.reconcileOrganization(t, organization.Name)
+	c.events()
+	return c
+}
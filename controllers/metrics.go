@@ -0,0 +1,140 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	k8sv1beta1 "github.com/flavio/organization-operator/api/v1beta1"
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+const metricsNamespace = "organization_operator"
+
+var (
+	organizationsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "organizations",
+			Help:      "Number of Organizations per phase",
+		},
+		[]string{"organization", "phase"},
+	)
+
+	spacesGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "spaces",
+			Help:      "Number of Spaces per Organization and phase",
+		},
+		[]string{"organization", "phase"},
+	)
+
+	managedObjectsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "managed_objects_total",
+			Help:      "Number of objects created, updated or deleted by the operator",
+		},
+		[]string{"organization", "kind", "operation"},
+	)
+
+	driftCorrectionsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "drift_corrections_total",
+			Help:      "Number of times the operator overwrote a change made by someone else to one of the objects it manages",
+		},
+		[]string{"organization", "kind"},
+	)
+)
+
+var organizationPhases = []k8sv1beta1.OrganizationPhase{
+	k8sv1beta1.OrganizationPhasePending,
+	k8sv1beta1.OrganizationPhaseReady,
+	k8sv1beta1.OrganizationPhaseDegraded,
+	k8sv1beta1.OrganizationPhaseTerminating,
+}
+
+var spacePhases = []k8sv1beta1.SpacePhase{
+	k8sv1beta1.SpacePhasePending,
+	k8sv1beta1.SpacePhaseReady,
+	k8sv1beta1.SpacePhaseTerminating,
+	k8sv1beta1.SpacePhaseError,
+}
+
+func init() {
+	metrics.Registry.MustRegister(
+		organizationsGauge,
+		spacesGauge,
+		managedObjectsCounter,
+		driftCorrectionsCounter,
+	)
+}
+
+// observeOrganization updates the gauges describing the given Organization
+// and its Spaces
+func observeOrganization(organization *k8sv1beta1.Organization) {
+	for _, phase := range organizationPhases {
+		value := 0.0
+		if organization.Status.Phase == phase {
+			value = 1
+		}
+		organizationsGauge.WithLabelValues(organization.Name, string(phase)).Set(value)
+	}
+
+	spacesPerPhase := map[k8sv1beta1.SpacePhase]int{}
+	for _, space := range organization.Status.Spaces {
+		spacesPerPhase[space.Phase]++
+	}
+	for _, phase := range spacePhases {
+		spacesGauge.WithLabelValues(organization.Name, string(phase)).Set(float64(spacesPerPhase[phase]))
+	}
+}
+
+// forgetOrganization removes the gauges of an Organization that doesn't
+// exist anymore
+func forgetOrganization(organizationName string) {
+	for _, phase := range organizationPhases {
+		organizationsGauge.DeleteLabelValues(organizationName, string(phase))
+	}
+	for _, phase := range spacePhases {
+		spacesGauge.DeleteLabelValues(organizationName, string(phase))
+	}
+}
+
+// observeOperation updates the counters of the objects managed on behalf
+// of the given Organization
+func observeOperation(organizationName, kind string, result controllerutil.OperationResult) {
+	switch result {
+	case controllerutil.OperationResultCreated:
+		managedObjectsCounter.WithLabelValues(organizationName, kind, "created").Inc()
+	case controllerutil.OperationResultUpdated:
+		managedObjectsCounter.WithLabelValues(organizationName, kind, "updated").Inc()
+	case common.OperationResultDriftCorrected:
+		managedObjectsCounter.WithLabelValues(organizationName, kind, "updated").Inc()
+		driftCorrectionsCounter.WithLabelValues(organizationName, kind).Inc()
+	}
+}
+
+// observeDeletion updates the counters when an object managed on behalf of
+// the given Organization is deleted
+func observeDeletion(organizationName, kind string) {
+	managedObjectsCounter.WithLabelValues(organizationName, kind, "deleted").Inc()
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	rbac "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	k8sv1beta1 "github.com/flavio/organization-operator/api/v1beta1"
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// The metrics are global: each test uses its own Organization name
+
+func TestObserveOrganization(t *testing.T) {
+	organization := newOrganization("observed")
+	organization.Status.Phase = k8sv1beta1.OrganizationPhaseDegraded
+	organization.Status.Spaces = []k8sv1beta1.SpaceSummary{
+		{Name: "web", Phase: k8sv1beta1.SpacePhaseReady},
+		{Name: "api", Phase: k8sv1beta1.SpacePhaseReady},
+		{Name: "docs", Phase: k8sv1beta1.SpacePhaseError},
+	}
+	observeOrganization(organization)
+
+	organizations := map[k8sv1beta1.OrganizationPhase]float64{
+		k8sv1beta1.OrganizationPhasePending:     0,
+		k8sv1beta1.OrganizationPhaseReady:       0,
+		k8sv1beta1.OrganizationPhaseDegraded:    1,
+		k8sv1beta1.OrganizationPhaseTerminating: 0,
+	}
+	for phase, expected := range organizations {
+		if value := testutil.ToFloat64(organizationsGauge.WithLabelValues("observed", string(phase))); value != expected {
+			t.Errorf("expected %v Organizations in phase %s, got %v", expected, phase, value)
+		}
+	}
+	spaces := map[k8sv1beta1.SpacePhase]float64{
+		k8sv1beta1.SpacePhasePending:     0,
+		k8sv1beta1.SpacePhaseReady:       2,
+		k8sv1beta1.SpacePhaseTerminating: 0,
+		k8sv1beta1.SpacePhaseError:       1,
+	}
+	for phase, expected := range spaces {
+		if value := testutil.ToFloat64(spacesGauge.WithLabelValues("observed", string(phase))); value != expected {
+			t.Errorf("expected %v Spaces in phase %s, got %v", expected, phase, value)
+		}
+	}
+
+	forgetOrganization("observed")
+	if organizationsGauge.DeleteLabelValues("observed", string(k8sv1beta1.OrganizationPhaseDegraded)) ||
+		spacesGauge.DeleteLabelValues("observed", string(k8sv1beta1.SpacePhaseReady)) {
+		t.Error("expected the gauges of the Organization to be removed")
+	}
+}
+
+func TestObserveOperation(t *testing.T) {
+	observeOperation("counted", "RoleBinding", controllerutil.OperationResultNone)
+	observeOperation("counted", "RoleBinding", controllerutil.OperationResultCreated)
+	observeOperation("counted", "RoleBinding", controllerutil.OperationResultUpdated)
+	observeOperation("counted", "RoleBinding", common.OperationResultDriftCorrected)
+	observeDeletion("counted", "RoleBinding")
+
+	counters := map[string]float64{
+		"created": 1,
+		"updated": 2,
+		"deleted": 1,
+	}
+	for operation, expected := range counters {
+		counter := managedObjectsCounter.WithLabelValues("counted", "RoleBinding", operation)
+		if value := testutil.ToFloat64(counter); value != expected {
+			t.Errorf("expected %v objects %s, got %v", expected, operation, value)
+		}
+	}
+	if value := testutil.ToFloat64(driftCorrectionsCounter.WithLabelValues("counted", "RoleBinding")); value != 1 {
+		t.Errorf("expected 1 drift correction, got %v", value)
+	}
+}
+
+func TestDriftCorrectionMetric(t *testing.T) {
+	c := newOrganizationCluster(t, newOrganization("drifted"), newSpace("drifted", "web"))
+	c.reconcileSpace(t, "drifted", "web")
+
+	roleBinding := &rbac.RoleBinding{}
+	c.get(t, "drifted-web-space", "viewers", roleBinding)
+	roleBinding.Subjects = []rbac.Subject{{
+		Kind:     rbac.UserKind,
+		Name:     "mallory",
+		APIGroup: rbac.GroupName,
+	}}
+	if err := c.Update(context.Background(), roleBinding); err != nil {
+		t.Fatal(err)
+	}
+	c.reconcileSpace(t, "drifted", "web")
+
+	if value := testutil.ToFloat64(driftCorrectionsCounter.WithLabelValues("drifted", "RoleBinding")); value != 1 {
+		t.Errorf("expected 1 drift correction, got %v", value)
+	}
+	// The two scope RoleBindings of the Organization and the three of the Space
+	if value := testutil.ToFloat64(managedObjectsCounter.WithLabelValues("drifted", "RoleBinding", "created")); value != 5 {
+		t.Errorf("expected 5 RoleBindings created, got %v", value)
+	}
+}
@@ -18,6 +18,9 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -25,23 +28,29 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	k8sv1alpha1 "github.com/flavio/organization-operator/api/v1alpha1"
+	k8sv1beta1 "github.com/flavio/organization-operator/api/v1beta1"
 	"github.com/flavio/organization-operator/pkg/common"
 )
 
 // OrganizationReconciler reconciles a Organization object
 type OrganizationReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=k8s.suse.com,resources=organizations,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=k8s.suse.com,resources=organizations/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=k8s.suse.com,resources=spaceextraconfigs;spacemoves,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *OrganizationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
@@ -51,28 +60,154 @@ func (r *OrganizationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 	reqLogger.Info("Reconciling Organization")
 
 	// Fetch the Organization instance
-	instance := &k8sv1alpha1.Organization{}
+	instance := &k8sv1beta1.Organization{}
 	err := r.Get(ctx, req.NamespacedName, instance)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile req.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			forgetOrganization(req.Name)
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object - requeue the req.
 		return ctrl.Result{}, err
 	}
 
-	scopeNamespace := namespaceForOrganizationSpaceObjects(instance)
-	if err := common.ReconcileNamespace(r, scopeNamespace, instance, r.Scheme, reqLogger, ctx); err != nil {
+	if instance.GetDeletionTimestamp() != nil {
+		if err = r.updateStatus(instance, nil, reqLogger, ctx); err != nil {
+			return ctrl.Result{}, err
+		}
+		return r.handleFinalizer(instance, reqLogger, ctx)
+	}
+	if err = r.addFinalizer(instance, reqLogger, ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	reconcileErr := r.reconcileOrganizationResources(instance, reqLogger, ctx)
+	if reconcileErr != nil {
+		r.Recorder.Event(instance, corev1.EventTypeWarning, eventReasonReconcileFailed, reconcileErr.Error())
+	}
+	if err := r.updateStatus(instance, reconcileErr, reqLogger, ctx); reconcileErr == nil {
+		reconcileErr = err
+	}
+
+	return ctrl.Result{}, reconcileErr
+}
+
+// addFinalizer adds the finalizer of the Organization when it's missing. The
+// defaulting webhook adds it to the new Organizations, the ones created by
+// older releases of the operator don't have it.
+func (r *OrganizationReconciler) addFinalizer(instance *k8sv1beta1.Organization, reqLogger logr.Logger, ctx context.Context) error {
+	for _, finalizer := range instance.GetFinalizers() {
+		if finalizer == common.OrganizationFinalizer {
+			return nil
+		}
+	}
+
+	reqLogger.Info("Adding finalizer")
+	instance.SetFinalizers(append(instance.GetFinalizers(), common.OrganizationFinalizer))
+	return r.Update(ctx, instance)
+}
+
+// handleFinalizer applies the deletion policy of the Organization: its
+// Spaces are deleted, or their removal is awaited, before removing the
+// finalizer. Once the finalizer is gone Kubernetes deletes the Organization
+// together with the objects it owns.
+func (r *OrganizationReconciler) handleFinalizer(instance *k8sv1beta1.Organization, reqLogger logr.Logger, ctx context.Context) (ctrl.Result, error) {
+	finalizerFound := false
+	newFinalizers := []string{}
+	for _, finalizer := range instance.GetFinalizers() {
+		if finalizer == common.OrganizationFinalizer {
+			finalizerFound = true
+		} else {
+			newFinalizers = append(newFinalizers, finalizer)
+		}
+	}
+	if !finalizerFound {
+		return ctrl.Result{}, nil
+	}
+
+	reqLogger.Info("Handling finalizer")
+	spaces := &k8sv1beta1.SpaceList{}
+	err := r.List(ctx, spaces,
+		client.InNamespace(common.ComputeSpacesNamespaceFromOrganizationName(instance.Name)))
+	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	if len(spaces.Items) > 0 {
+		// The removal of the Spaces triggers a new reconciliation of the
+		// Organization, there's no need to requeue the request
+		if instance.Spec.DeletionPolicy == k8sv1beta1.OrganizationDeletionPolicyBlock {
+			reqLogger.Info("Organization cannot be deleted while it has Spaces",
+				"Spaces", len(spaces.Items))
+			r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventReasonDeletionBlocked,
+				"The deletion policy is %s and the Organization still has %d Spaces",
+				instance.Spec.DeletionPolicy, len(spaces.Items))
+			return ctrl.Result{}, nil
+		}
+
+		for i := range spaces.Items {
+			space := &spaces.Items[i]
+			if space.GetDeletionTimestamp() != nil {
+				continue
+			}
+			reqLogger.Info("Deleting Space of the Organization", "Space.Name", space.Name)
+			if err = r.Delete(ctx, space); err != nil && !errors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+			r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonDeleted,
+				"Deleted Space %s/%s", space.Namespace, space.Name)
+			observeDeletion(instance.Name, "Space")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	instance.SetFinalizers(newFinalizers)
+	if err = r.Update(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcileOrganizationResources creates or updates the Namespace holding the
+// Spaces of the Organization and the scope Roles and RoleBindings defined
+// inside of it
+func (r *OrganizationReconciler) reconcileOrganizationResources(
+	instance *k8sv1beta1.Organization,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	scopeNamespace := namespaceForOrganizationSpaceObjects(instance)
+	result, err := common.ReconcileNamespace(r, scopeNamespace, instance, r.Scheme, reqLogger, ctx)
+	if err != nil {
+		return err
+	}
+	recordOperation(r.Recorder, instance, instance.Name, "Namespace", "", scopeNamespace.Name, result)
+
+	err = r.reconcileScopeRBAC(scopeNamespace, instance, reqLogger, ctx)
+	if err != nil {
+		setOrganizationCondition(instance, k8sv1beta1.OrganizationConditionRBACSynced,
+			corev1.ConditionFalse, "RBACReconcileFailed", err.Error())
+		return err
+	}
+	setOrganizationCondition(instance, k8sv1beta1.OrganizationConditionRBACSynced,
+		corev1.ConditionTrue, "RBACReconciled", "")
+
+	return nil
+}
+
+// reconcileScopeRBAC creates or updates the Roles and RoleBindings granting
+// access to the Space objects of the Organization
+func (r *OrganizationReconciler) reconcileScopeRBAC(
+	scopeNamespace *corev1.Namespace,
+	instance *k8sv1beta1.Organization,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
 	// Define a new RBAC Role that allows to read Scope objects inside of the namespace
 	roleScopeReader := newRoleScopeReader(scopeNamespace)
-	if err = r.reconcileRBACRole(roleScopeReader, instance, reqLogger, ctx); err != nil {
-		return ctrl.Result{}, err
+	if err := r.reconcileRBACRole(roleScopeReader, instance, reqLogger, ctx); err != nil {
+		return err
 	}
 
 	// Create a RoleBinding: all the viewers and editors of an Organization
@@ -80,56 +215,216 @@ func (r *OrganizationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 	roleBinding := common.NewRoleBinding(
 		roleScopeReader.Name,
 		roleScopeReader.Namespace,
-		[]string{},
-		append(instance.Spec.EditorGroups, instance.Spec.ViewerGroups...),
+		common.MergeMembers(instance.Spec.Editors, instance.Spec.Viewers),
+		common.MergeMembers(instance.Spec.EditorGroups, instance.Spec.ViewerGroups),
+		common.MergeMembers(instance.Spec.EditorServiceAccounts, instance.Spec.ViewerServiceAccounts),
 		rbac.RoleRef{
 			APIGroup: "rbac.authorization.k8s.io",
 			Kind:     "Role",
 			Name:     roleScopeReader.Name,
 		},
 	)
-	if err = common.ReconcileRBACRoleBinding(r, roleBinding, instance, r.Scheme, reqLogger, ctx); err != nil {
-		return ctrl.Result{}, err
+	if err := r.reconcileRBACRoleBinding(roleBinding, instance, reqLogger, ctx); err != nil {
+		return err
 	}
 
 	// Define a new RBAC Role that allows to admin Scope objects inside of the namespace
 	roleScopeAdmin := newRoleScopeAdmin(scopeNamespace)
-	if err = r.reconcileRBACRole(roleScopeAdmin, instance, reqLogger, ctx); err != nil {
-		return ctrl.Result{}, err
+	if err := r.reconcileRBACRole(roleScopeAdmin, instance, reqLogger, ctx); err != nil {
+		return err
 	}
 	// Create a RoleBinding: only the admins of an Organization
 	// can alter the Scope objects related with the Organization
 	roleBinding = common.NewRoleBinding(
 		roleScopeAdmin.Name,
 		roleScopeAdmin.Namespace,
-		[]string{},
-		append(instance.Spec.EditorGroups, instance.Spec.ViewerGroups...),
+		instance.Spec.Admins,
+		instance.Spec.AdminGroups,
+		instance.Spec.AdminServiceAccounts,
 		rbac.RoleRef{
 			APIGroup: "rbac.authorization.k8s.io",
 			Kind:     "Role",
 			Name:     roleScopeAdmin.Name,
 		},
 	)
-	if err = common.ReconcileRBACRoleBinding(r, roleBinding, instance, r.Scheme, reqLogger, ctx); err != nil {
-		return ctrl.Result{}, err
+	return r.reconcileRBACRoleBinding(roleBinding, instance, reqLogger, ctx)
+}
+
+func (r *OrganizationReconciler) reconcileRBACRoleBinding(
+	roleBinding *rbac.RoleBinding,
+	instance *k8sv1beta1.Organization,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	result, err := common.ReconcileRBACRoleBinding(r, roleBinding, instance, r.Scheme, reqLogger, ctx)
+	if err != nil {
+		return err
 	}
+	recordOperation(r.Recorder, instance, instance.Name, "RoleBinding", roleBinding.Namespace, roleBinding.Name, result)
+	return nil
+}
 
-	return ctrl.Result{}, nil
+// updateStatus summarises the Spaces owned by the Organization, computes its
+// phase and writes its status through the status subresource
+func (r *OrganizationReconciler) updateStatus(
+	instance *k8sv1beta1.Organization,
+	reconcileErr error,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	instance.Status.ObservedGeneration = instance.Generation
+	instance.Status.SpacesNamespace = common.ComputeSpacesNamespaceFromOrganizationName(instance.Name)
+
+	spaces := &k8sv1beta1.SpaceList{}
+	if err := r.List(ctx, spaces, client.InNamespace(instance.Status.SpacesNamespace)); err != nil {
+		reqLogger.Error(err, "Cannot list Spaces of Organization")
+		return err
+	}
+
+	summaries := []k8sv1beta1.SpaceSummary{}
+	spacesInError := []string{}
+	for _, space := range spaces.Items {
+		summaries = append(summaries, k8sv1beta1.SpaceSummary{
+			Name:  space.Name,
+			Phase: space.Status.Phase,
+		})
+		if space.Status.Phase == k8sv1beta1.SpacePhaseError {
+			spacesInError = append(spacesInError, space.Name)
+		}
+	}
+	instance.Status.Spaces = summaries
+	instance.Status.SpaceCount = int32(len(summaries))
+
+	if len(instance.Spec.Budget) > 0 {
+		extraConfigs := &k8sv1beta1.SpaceExtraConfigList{}
+		if err := r.List(ctx, extraConfigs, client.InNamespace(instance.Status.SpacesNamespace)); err != nil {
+			reqLogger.Error(err, "Cannot list SpaceExtraConfigs of Organization")
+			return err
+		}
+		instance.Status.AllocatedBudget = k8sv1beta1.AllocatedBudget(instance.Spec.Budget, spaces.Items, extraConfigs.Items)
+		instance.Status.AvailableBudget = k8sv1beta1.AvailableBudget(instance.Spec.Budget, instance.Status.AllocatedBudget)
+	} else {
+		instance.Status.AllocatedBudget = nil
+		instance.Status.AvailableBudget = nil
+	}
+
+	// The webhooks check the budget against the Spaces they can see,
+	// concurrent changes can still push the Spaces over it
+	exceeded := []string{}
+	for name, available := range instance.Status.AvailableBudget {
+		if available.Sign() < 0 {
+			exceeded = append(exceeded, string(name))
+		}
+	}
+	sort.Strings(exceeded)
+	budgetMessage := ""
+	if len(exceeded) > 0 {
+		budgetMessage = fmt.Sprintf("The Spaces exceed the budget for: %s", strings.Join(exceeded, ", "))
+		setOrganizationCondition(instance, k8sv1beta1.OrganizationConditionBudgetExceeded,
+			corev1.ConditionTrue, "BudgetExceeded", budgetMessage)
+	} else {
+		setOrganizationCondition(instance, k8sv1beta1.OrganizationConditionBudgetExceeded,
+			corev1.ConditionFalse, "WithinBudget", "")
+	}
+
+	if reconcileErr != nil {
+		setOrganizationCondition(instance, k8sv1beta1.OrganizationConditionReady,
+			corev1.ConditionFalse, "ReconcileFailed", reconcileErr.Error())
+		setOrganizationCondition(instance, k8sv1beta1.OrganizationConditionDegraded,
+			corev1.ConditionTrue, "ReconcileFailed", reconcileErr.Error())
+	} else {
+		setOrganizationCondition(instance, k8sv1beta1.OrganizationConditionReady,
+			corev1.ConditionTrue, "ResourcesReconciled", "")
+		if len(spacesInError) > 0 {
+			setOrganizationCondition(instance, k8sv1beta1.OrganizationConditionDegraded,
+				corev1.ConditionTrue, "SpacesInError",
+				fmt.Sprintf("Spaces in error: %s", strings.Join(spacesInError, ", ")))
+		} else if len(exceeded) > 0 {
+			setOrganizationCondition(instance, k8sv1beta1.OrganizationConditionDegraded,
+				corev1.ConditionTrue, "BudgetExceeded", budgetMessage)
+		} else {
+			setOrganizationCondition(instance, k8sv1beta1.OrganizationConditionDegraded,
+				corev1.ConditionFalse, "AsExpected", "")
+		}
+	}
+
+	switch {
+	case instance.GetDeletionTimestamp() != nil:
+		instance.Status.Phase = k8sv1beta1.OrganizationPhaseTerminating
+	case k8sv1beta1.IsConditionTrue(instance.Status.Conditions, k8sv1beta1.OrganizationConditionDegraded):
+		instance.Status.Phase = k8sv1beta1.OrganizationPhaseDegraded
+	case k8sv1beta1.IsConditionTrue(instance.Status.Conditions, k8sv1beta1.OrganizationConditionReady):
+		instance.Status.Phase = k8sv1beta1.OrganizationPhaseReady
+	default:
+		instance.Status.Phase = k8sv1beta1.OrganizationPhasePending
+	}
+	observeOrganization(instance)
+
+	if err := r.Status().Update(ctx, instance); err != nil {
+		reqLogger.Error(err, "Cannot update status of Organization")
+		return err
+	}
+	return nil
+}
+
+func setOrganizationCondition(instance *k8sv1beta1.Organization, conditionType string, status corev1.ConditionStatus, reason, message string) {
+	k8sv1beta1.SetCondition(&instance.Status.Conditions, k8sv1beta1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: instance.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
 }
 
 func (r *OrganizationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&k8sv1alpha1.Organization{}).
+		For(&k8sv1beta1.Organization{}).
 		Owns(&corev1.Namespace{}).
 		Owns(&rbac.Role{}).
 		Owns(&rbac.RoleBinding{}).
+		// Watch for changes to the Space objects, the status of the
+		// Organization summarises the state of its Spaces
+		Watches(
+			&source.Kind{Type: &k8sv1beta1.Space{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.organizationOfSpace),
+			},
+		).
+		// The quota of the SpaceExtraConfig objects counts against the
+		// budget of the Organization
+		Watches(
+			&source.Kind{Type: &k8sv1beta1.SpaceExtraConfig{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.organizationOfSpace),
+			},
+		).
 		Complete(r)
 }
 
-func namespaceForOrganizationSpaceObjects(cr *k8sv1alpha1.Organization) *corev1.Namespace {
+// organizationOfSpace returns a reconcile request for the Organization owning
+// the given Space or SpaceExtraConfig
+func (r *OrganizationReconciler) organizationOfSpace(a handler.MapObject) []ctrl.Request {
+	org, found, err := common.OrganizationOfSpacesNamespace(r, a.Meta.GetNamespace(), context.Background())
+	if err != nil {
+		r.Log.Error(err, "Cannot find the Organization owning object",
+			"Name", a.Meta.GetName(),
+			"Namespace", a.Meta.GetNamespace())
+		return []ctrl.Request{}
+	}
+	if !found {
+		return []ctrl.Request{}
+	}
+	return []ctrl.Request{
+		{
+			NamespacedName: client.ObjectKey{Name: org},
+		},
+	}
+}
+
+func namespaceForOrganizationSpaceObjects(cr *k8sv1beta1.Organization) *corev1.Namespace {
 	labels := map[string]string{
-		"app": cr.Name,
+		"app":                       cr.Name,
+		common.SpacesNamespaceLabel: cr.Name,
 	}
 	return &corev1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
@@ -147,8 +442,8 @@ func newRoleScopeReader(namespace *corev1.Namespace) *rbac.Role {
 		},
 		Rules: []rbac.PolicyRule{
 			{
-				APIGroups: []string{"k8s.suse.com/v1alpha1"},
-				Resources: []string{"scopes"},
+				APIGroups: []string{k8sv1beta1.GroupVersion.Group},
+				Resources: []string{"spaces", "spaceextraconfigs", "spacemoves"},
 				Verbs:     []string{"get", "list", "watch"},
 			},
 		},
@@ -163,8 +458,8 @@ func newRoleScopeAdmin(namespace *corev1.Namespace) *rbac.Role {
 		},
 		Rules: []rbac.PolicyRule{
 			{
-				APIGroups: []string{"k8s.suse.com/v1alpha1"},
-				Resources: []string{"scopes"},
+				APIGroups: []string{k8sv1beta1.GroupVersion.Group},
+				Resources: []string{"spaces", "spaceextraconfigs", "spacemoves"},
 				Verbs: []string{
 					"get", "list", "watch",
 					"create", "update", "patch", "delete"},
@@ -175,7 +470,7 @@ func newRoleScopeAdmin(namespace *corev1.Namespace) *rbac.Role {
 
 func (r *OrganizationReconciler) reconcileRBACRole(
 	role *rbac.Role,
-	instance *k8sv1alpha1.Organization,
+	instance *k8sv1beta1.Organization,
 	reqLogger logr.Logger,
 	ctx context.Context) error {
 	// Set Organization instance as the owner and controller
@@ -212,6 +507,7 @@ func (r *OrganizationReconciler) reconcileRBACRole(
 			"Created RBAC Role",
 			"Role.Namespace", role.Namespace,
 			"Role.Name", role.Name)
+		recordOperation(r.Recorder, instance, instance.Name, "Role", role.Namespace, role.Name, controllerutil.OperationResultCreated)
 		return nil
 	} else if err != nil {
 		return err
@@ -228,7 +524,10 @@ func (r *OrganizationReconciler) reconcileRBACRole(
 		reqLogger.Info("Updating RBAC Role to have the same policy rules",
 			"Namespace", found.Namespace,
 			"Name", found.Name)
-		return r.Client.Update(ctx, found)
+		if err = r.Client.Update(ctx, found); err != nil {
+			return err
+		}
+		recordOperation(r.Recorder, instance, instance.Name, "Role", found.Namespace, found.Name, controllerutil.OperationResultUpdated)
 	}
 
 	return nil
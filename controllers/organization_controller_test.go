@@ -0,0 +1,235 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	k8sv1beta1 "github.com/flavio/organization-operator/api/v1beta1"
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// spaceWithPhase returns a Space of the Organization acme that has already
+// been reconciled
+func spaceWithPhase(name string, phase k8sv1beta1.SpacePhase) *k8sv1beta1.Space {
+	space := newSpace("acme", name)
+	space.Status.Phase = phase
+	return space
+}
+
+func TestOrganizationStatus(t *testing.T) {
+	organization := newOrganization("acme")
+	organization.Generation = 2
+	c := newFakeCluster(
+		organization,
+		spaceWithPhase("web", k8sv1beta1.SpacePhaseReady),
+		spaceWithPhase("api", k8sv1beta1.SpacePhasePending),
+	)
+	c.reconcileOrganization(t, "acme")
+
+	organization = &k8sv1beta1.Organization{}
+	c.get(t, "", "acme", organization)
+	status := organization.Status
+	if status.SpacesNamespace != "acme-spaces" {
+		t.Errorf("expected spaces Namespace acme-spaces, got %q", status.SpacesNamespace)
+	}
+	if status.SpaceCount != 2 {
+		t.Errorf("expected 2 Spaces, got %d", status.SpaceCount)
+	}
+	spaces := append([]k8sv1beta1.SpaceSummary{}, status.Spaces...)
+	sort.Slice(spaces, func(i, j int) bool { return spaces[i].Name < spaces[j].Name })
+	expected := []k8sv1beta1.SpaceSummary{
+		{Name: "api", Phase: k8sv1beta1.SpacePhasePending},
+		{Name: "web", Phase: k8sv1beta1.SpacePhaseReady},
+	}
+	if !reflect.DeepEqual(spaces, expected) {
+		t.Errorf("expected Spaces %v, got %v", expected, spaces)
+	}
+	if status.Phase != k8sv1beta1.OrganizationPhaseReady {
+		t.Errorf("expected phase %s, got %s", k8sv1beta1.OrganizationPhaseReady, status.Phase)
+	}
+	if status.ObservedGeneration != 2 {
+		t.Errorf("expected observedGeneration 2, got %d", status.ObservedGeneration)
+	}
+	assertCondition(t, status.Conditions, k8sv1beta1.OrganizationConditionReady,
+		corev1.ConditionTrue, "ResourcesReconciled")
+	assertCondition(t, status.Conditions, k8sv1beta1.OrganizationConditionRBACSynced,
+		corev1.ConditionTrue, "RBACReconciled")
+	assertCondition(t, status.Conditions, k8sv1beta1.OrganizationConditionDegraded,
+		corev1.ConditionFalse, "AsExpected")
+
+	// The scope Roles and RoleBindings reported as synced exist
+	for _, name := range []string{"scope-reader", "scope-admin"} {
+		c.get(t, "acme-spaces", name, &rbac.Role{})
+		c.get(t, "acme-spaces", name, &rbac.RoleBinding{})
+	}
+}
+
+func TestOrganizationStatusSpacesInError(t *testing.T) {
+	c := newFakeCluster(
+		newOrganization("acme"),
+		spaceWithPhase("web", k8sv1beta1.SpacePhaseReady),
+		spaceWithPhase("api", k8sv1beta1.SpacePhaseError),
+	)
+	c.reconcileOrganization(t, "acme")
+
+	organization := &k8sv1beta1.Organization{}
+	c.get(t, "", "acme", organization)
+	if organization.Status.Phase != k8sv1beta1.OrganizationPhaseDegraded {
+		t.Errorf("expected phase %s, got %s", k8sv1beta1.OrganizationPhaseDegraded, organization.Status.Phase)
+	}
+	assertCondition(t, organization.Status.Conditions, k8sv1beta1.OrganizationConditionReady,
+		corev1.ConditionTrue, "ResourcesReconciled")
+	assertCondition(t, organization.Status.Conditions, k8sv1beta1.OrganizationConditionDegraded,
+		corev1.ConditionTrue, "SpacesInError")
+	degraded := k8sv1beta1.FindCondition(organization.Status.Conditions, k8sv1beta1.OrganizationConditionDegraded)
+	if degraded != nil && degraded.Message != "Spaces in error: api" {
+		t.Errorf("unexpected message %q", degraded.Message)
+	}
+}
+
+func TestOrganizationAddsFinalizer(t *testing.T) {
+	c := newFakeCluster(newOrganization("acme"))
+	c.reconcileOrganization(t, "acme")
+
+	organization := &k8sv1beta1.Organization{}
+	c.get(t, "", "acme", organization)
+	expected := []string{common.OrganizationFinalizer}
+	if !reflect.DeepEqual(organization.GetFinalizers(), expected) {
+		t.Errorf("expected finalizers %v, got %v", expected, organization.GetFinalizers())
+	}
+}
+
+// deletedOrganization returns the Organization acme, with the given deletion
+// policy, as it looks once its deletion has been requested
+func deletedOrganization(policy k8sv1beta1.OrganizationDeletionPolicy) *k8sv1beta1.Organization {
+	organization := newOrganization("acme")
+	organization.Spec.DeletionPolicy = policy
+	organization.Finalizers = []string{common.OrganizationFinalizer}
+	now := metav1.Now()
+	organization.DeletionTimestamp = &now
+	return organization
+}
+
+// assertOrganizationFinalizer checks whether the Organization acme still
+// has its finalizer
+func assertOrganizationFinalizer(t *testing.T, c *fakeCluster, expected bool) {
+	t.Helper()
+	organization := &k8sv1beta1.Organization{}
+	c.get(t, "", "acme", organization)
+	found := false
+	for _, finalizer := range organization.GetFinalizers() {
+		found = found || finalizer == common.OrganizationFinalizer
+	}
+	if found != expected {
+		t.Errorf("expected the finalizer to be present: %v, got finalizers %v",
+			expected, organization.GetFinalizers())
+	}
+}
+
+func TestOrganizationDeletionCascade(t *testing.T) {
+	terminating := newSpace("acme", "api")
+	now := metav1.Now()
+	terminating.DeletionTimestamp = &now
+	c := newFakeCluster(
+		deletedOrganization(k8sv1beta1.OrganizationDeletionPolicyCascade),
+		newSpace("acme", "web"),
+		terminating,
+	)
+
+	c.reconcileOrganization(t, "acme")
+	// The Space already being deleted is left to its own finalizer
+	expected := []string{"Normal Deleted Deleted Space acme-spaces/web"}
+	if events := c.events(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+	err := c.Get(context.Background(), client.ObjectKey{Name: "web", Namespace: "acme-spaces"}, &k8sv1beta1.Space{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected Space web to be deleted, got %v", err)
+	}
+	assertOrganizationFinalizer(t, c, true)
+
+	if err = c.Delete(context.Background(), terminating); err != nil {
+		t.Fatal(err)
+	}
+	c.reconcileOrganization(t, "acme")
+	assertOrganizationFinalizer(t, c, false)
+}
+
+func TestOrganizationDeletionBlock(t *testing.T) {
+	space := newSpace("acme", "web")
+	c := newFakeCluster(deletedOrganization(k8sv1beta1.OrganizationDeletionPolicyBlock), space)
+
+	c.reconcileOrganization(t, "acme")
+	expected := []string{
+		"Warning DeletionBlocked The deletion policy is Block and the Organization still has 1 Spaces",
+	}
+	if events := c.events(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+	c.get(t, "acme-spaces", "web", &k8sv1beta1.Space{})
+	assertOrganizationFinalizer(t, c, true)
+
+	if err := c.Delete(context.Background(), space); err != nil {
+		t.Fatal(err)
+	}
+	c.reconcileOrganization(t, "acme")
+	assertOrganizationFinalizer(t, c, false)
+}
+
+func TestOrganizationBudgetExceeded(t *testing.T) {
+	organization := newOrganization("acme")
+	organization.Spec.Budget = corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("1")}
+	// The Space has been created while the budget was larger
+	space := spaceWithPhase("web", k8sv1beta1.SpacePhaseReady)
+	space.Spec.Quota = &k8sv1beta1.SpaceQuota{Hard: corev1.ResourceList{
+		corev1.ResourceRequestsCPU: resource.MustParse("2"),
+	}}
+	c := newFakeCluster(organization, space)
+	c.reconcileOrganization(t, "acme")
+
+	organization = &k8sv1beta1.Organization{}
+	c.get(t, "", "acme", organization)
+	if organization.Status.Phase != k8sv1beta1.OrganizationPhaseDegraded {
+		t.Errorf("expected phase %s, got %s", k8sv1beta1.OrganizationPhaseDegraded, organization.Status.Phase)
+	}
+	message := "The Spaces exceed the budget for: requests.cpu"
+	for _, conditionType := range []string{
+		k8sv1beta1.OrganizationConditionBudgetExceeded,
+		k8sv1beta1.OrganizationConditionDegraded,
+	} {
+		assertCondition(t, organization.Status.Conditions, conditionType, corev1.ConditionTrue, "BudgetExceeded")
+		condition := k8sv1beta1.FindCondition(organization.Status.Conditions, conditionType)
+		if condition != nil && condition.Message != message {
+			t.Errorf("unexpected message %q", condition.Message)
+		}
+	}
+	available := organization.Status.AvailableBudget[corev1.ResourceRequestsCPU]
+	if available.Cmp(resource.MustParse("-1")) != 0 {
+		t.Errorf("expected an available budget of -1 CPU, got %s", available.String())
+	}
+}
@@ -18,34 +18,61 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
 	rbac "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	k8sv1alpha1 "github.com/flavio/organization-operator/api/v1alpha1"
+	k8sv1beta1 "github.com/flavio/organization-operator/api/v1beta1"
 	"github.com/flavio/organization-operator/pkg/common"
 )
 
 const labelOrganization = "organization-operator.k8s.suse.com/organization"
 const labelSpace = "organization-operator.k8s.suse.com/space"
 
+// Names of the objects created inside of the Namespace of a Space
+const resourceQuotaName = "space-quota"
+const limitRangeName = "space-limits"
+const networkPolicyName = "space-isolation"
+
+// Bounds of the delay between two checks of the termination of the Namespace
+// of a Space being deleted
+const minNamespaceTerminationBackoff = 5 * time.Second
+const maxNamespaceTerminationBackoff = 5 * time.Minute
+
 // SpaceReconciler reconciles a Space object
 type SpaceReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// ClusterRoles granted to the admins, editors and viewers of the
+	// Spaces, each Organization can override them
+	ClusterRoles k8sv1beta1.ClusterRoleMapping
 }
 
 // +kubebuilder:rbac:groups=k8s.suse.com,resources=spaces,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=k8s.suse.com,resources=spaces/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=k8s.suse.com,resources=spaceextraconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=resourcequotas;limitranges,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles;roles,verbs=bind
 
 func (r *SpaceReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
@@ -54,7 +81,7 @@ func (r *SpaceReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	reqLogger.Info("Reconciling Space")
 
 	// Fetch the Space instance
-	instance := &k8sv1alpha1.Space{}
+	instance := &k8sv1beta1.Space{}
 	err := r.Get(ctx, req.NamespacedName, instance)
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -67,9 +94,18 @@ func (r *SpaceReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
-	organizationName, err := common.ComputeOrganizationNameFromSpaceNamespace(req.Namespace)
+	organizationName, organizationKnown, err := common.OrganizationOfSpacesNamespace(r, req.Namespace, ctx)
 	if err != nil {
-		reqLogger.Error(err, "Cannot deduce organization name")
+		return ctrl.Result{}, err
+	}
+	beingDeleted := instance.GetDeletionTimestamp() != nil
+	if !organizationKnown {
+		reqLogger.Info("Cannot deduce organization name", "Namespace", req.Namespace)
+		if beingDeleted {
+			// The Namespaces of the Space are found using the name of
+			// the Organization, try again later
+			return ctrl.Result{}, fmt.Errorf("cannot find the Organization owning Namespace %s", req.Namespace)
+		}
 	}
 	organization, err := r.organizationOwningSpace(organizationName, reqLogger, ctx)
 	if err != nil {
@@ -78,20 +114,61 @@ func (r *SpaceReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			"Space.Namespace", instance.Namespace,
 			"Space.Name", instance.Name,
 			"error", err)
-		return ctrl.Result{}, err
+		setSpaceCondition(instance, k8sv1beta1.SpaceConditionOrganizationFound,
+			corev1.ConditionFalse, "OrganizationNotFound", err.Error())
+		if !beingDeleted {
+			r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventReasonOrganizationMissing,
+				"Cannot find organization %s owning the Space: %v", organizationName, err)
+			return ctrl.Result{}, r.updateStatus(instance, err, reqLogger, ctx)
+		}
+	} else {
+		reqLogger.Info("Organization found")
+		setSpaceCondition(instance, k8sv1beta1.SpaceConditionOrganizationFound,
+			corev1.ConditionTrue, "OrganizationFound", "")
 	}
-	reqLogger.Info("Organization found")
 
-	beingDeleted := instance.GetDeletionTimestamp() != nil
 	if beingDeleted {
-		return r.handleFinalizer(instance, organization, reqLogger, ctx)
+		// The Space can be finalized even when its Organization is gone:
+		// the objects to clean up are found using the labels set by the
+		// operator
+		return r.handleFinalizer(instance, organizationName, reqLogger, ctx)
+	}
+
+	err = r.reconcileSpaceResources(instance, organization, reqLogger, ctx)
+	if err != nil {
+		r.Recorder.Event(instance, corev1.EventTypeWarning, eventReasonReconcileFailed, err.Error())
 	}
+	if statusErr := r.updateStatus(instance, err, reqLogger, ctx); err == nil {
+		err = statusErr
+	}
+
+	return ctrl.Result{}, err
+}
+
+// reconcileSpaceResources creates or updates all the resources associated
+// with the Space. The conditions of the Space are updated along the way.
+func (r *SpaceReconciler) reconcileSpaceResources(
+	instance *k8sv1beta1.Space,
+	organization *k8sv1beta1.Organization,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	extraConfig, err := r.extraConfigOfSpace(instance, ctx)
+	if err != nil {
+		return err
+	}
+
+	namespaceCR := namespaceAssociatedWithSpace(instance, organization, extraConfig)
+	instance.Status.Namespace = namespaceCR.Name
 
-	namespaceCR := namespaceAssociatedWithSpace(instance, organization)
 	reqLogger.Info(
 		"Reconciling Namespace associated with Space",
 		"Namespace", namespaceCR.Name)
-	err = common.ReconcileNamespace(
+	if err = r.claimNamespace(instance, organization, namespaceCR, reqLogger, ctx); err != nil {
+		setSpaceCondition(instance, k8sv1beta1.SpaceConditionNamespaceReady,
+			corev1.ConditionFalse, "NamespaceNotManaged", err.Error())
+		return err
+	}
+	result, err := common.ReconcileNamespace(
 		r,
 		namespaceCR,
 		nil,
@@ -99,85 +176,393 @@ func (r *SpaceReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		reqLogger,
 		ctx)
 	if err != nil {
-		return ctrl.Result{}, err
+		setSpaceCondition(instance, k8sv1beta1.SpaceConditionNamespaceReady,
+			corev1.ConditionFalse, "NamespaceReconcileFailed", err.Error())
+		return err
 	}
+	recordOperation(r.Recorder, instance, organization.Name, "Namespace", "", namespaceCR.Name, result)
+	setSpaceCondition(instance, k8sv1beta1.SpaceConditionNamespaceReady,
+		corev1.ConditionTrue, "NamespaceReconciled", "")
 
-	roleBindingLabels := map[string]string{
-		labelOrganization: organization.Name,
-		labelSpace:        instance.Name,
+	roleBindings := mergeRoleBindings(
+		roleBindingsAssociatedWithSpace(instance, organization, namespaceCR.Name, r.ClusterRoles),
+		customRoleBindingsOfSpace(instance, organization, namespaceCR.Name, reqLogger),
+		extraRoleBindingsOfSpace(instance, organization, namespaceCR.Name, extraConfig, reqLogger))
+	for _, roleBinding := range roleBindings {
+		reqLogger.Info(
+			"Reconciling RoleBinding",
+			"Namespace", namespaceCR.Name,
+			"RoleBinding", roleBinding.Name)
+		if err = r.checkRoleBindingOwnership(instance, organization, roleBinding, ctx); err != nil {
+			setSpaceCondition(instance, k8sv1beta1.SpaceConditionRBACReady,
+				corev1.ConditionFalse, "RoleBindingNotManaged", err.Error())
+			return err
+		}
+		result, err = common.ReconcileRBACRoleBinding(r, roleBinding, nil, nil, reqLogger, ctx)
+		if err != nil {
+			setSpaceCondition(instance, k8sv1beta1.SpaceConditionRBACReady,
+				corev1.ConditionFalse, "RoleBindingReconcileFailed", err.Error())
+			return err
+		}
+		recordOperation(r.Recorder, instance, organization.Name, "RoleBinding", roleBinding.Namespace, roleBinding.Name, result)
+	}
+	if err = r.deleteStaleRoleBindings(instance, organization, namespaceCR.Name, roleBindings, reqLogger, ctx); err != nil {
+		setSpaceCondition(instance, k8sv1beta1.SpaceConditionRBACReady,
+			corev1.ConditionFalse, "RoleBindingReconcileFailed", err.Error())
+		return err
 	}
+	setSpaceCondition(instance, k8sv1beta1.SpaceConditionRBACReady,
+		corev1.ConditionTrue, "RoleBindingsReconciled", "")
 
-	// Create a RoleBinding for admin groups and users
-	roleBinding := common.NewRoleBinding(
-		"administrators",
-		namespaceCR.Name,
-		instance.Spec.Admins,
-		append(organization.Spec.AdminGroups, instance.Spec.AdminGroups...),
-		rbac.RoleRef{
-			APIGroup: "rbac.authorization.k8s.io",
-			Kind:     "ClusterRole",
-			Name:     "admin",
+	err = r.reconcileResourceQuota(instance, organization, namespaceCR.Name,
+		resourceQuotaOfSpace(instance, extraConfig), reqLogger, ctx)
+	if err != nil {
+		setSpaceCondition(instance, k8sv1beta1.SpaceConditionQuotaReady,
+			corev1.ConditionFalse, "ResourceQuotaReconcileFailed", err.Error())
+		return err
+	}
+	setSpaceCondition(instance, k8sv1beta1.SpaceConditionQuotaReady,
+		corev1.ConditionTrue, "ResourceQuotaReconciled", "")
+
+	err = r.reconcileLimitRange(instance, organization, namespaceCR.Name,
+		limitRangeOfSpace(instance, organization, extraConfig), reqLogger, ctx)
+	if err != nil {
+		setSpaceCondition(instance, k8sv1beta1.SpaceConditionLimitRangeReady,
+			corev1.ConditionFalse, "LimitRangeReconcileFailed", err.Error())
+		return err
+	}
+	setSpaceCondition(instance, k8sv1beta1.SpaceConditionLimitRangeReady,
+		corev1.ConditionTrue, "LimitRangeReconciled", "")
+
+	err = r.reconcileNetworkPolicy(instance, organization, namespaceCR.Name, reqLogger, ctx)
+	if err != nil {
+		setSpaceCondition(instance, k8sv1beta1.SpaceConditionNetworkPolicyReady,
+			corev1.ConditionFalse, "NetworkPolicyReconcileFailed", err.Error())
+		return err
+	}
+	setSpaceCondition(instance, k8sv1beta1.SpaceConditionNetworkPolicyReady,
+		corev1.ConditionTrue, "NetworkPolicyReconciled", "")
+
+	return nil
+}
+
+// claimNamespace ensures the Namespace of the Space can be managed by the
+// operator. A Namespace that already exists is changed only when it belongs
+// to the Space or when the Space asked to adopt it.
+func (r *SpaceReconciler) claimNamespace(
+	instance *k8sv1beta1.Space,
+	organization *k8sv1beta1.Organization,
+	namespace *corev1.Namespace,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	found := &corev1.Namespace{}
+	err := r.Get(ctx, client.ObjectKey{Name: namespace.Name}, found)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	labels := found.GetLabels()
+	if labels[labelOrganization] == organization.Name && labels[labelSpace] == instance.Name {
+		return nil
+	}
+	if space, managed := labels[labelSpace]; managed {
+		return fmt.Errorf("Namespace %s is managed by Space %s of Organization %s",
+			found.Name, space, labels[labelOrganization])
+	}
+	if instance.Spec.AdoptNamespace != found.Name {
+		return fmt.Errorf("Namespace %s already exists and is not managed by the operator", found.Name)
+	}
+	if found.GetAnnotations()[common.AdoptableByAnnotation] != organization.Name {
+		return fmt.Errorf("Namespace %s cannot be adopted by Organization %s, the %s annotation is missing",
+			found.Name, organization.Name, common.AdoptableByAnnotation)
+	}
+
+	if err = common.AdoptNamespace(r, found, namespace, reqLogger, ctx); err != nil {
+		return err
+	}
+	r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonNamespaceAdopted,
+		"Adopted Namespace %s", found.Name)
+	return nil
+}
+
+// checkRoleBindingOwnership ensures the RoleBinding doesn't replace one
+// created by someone else, like the ones found inside of an adopted Namespace
+func (r *SpaceReconciler) checkRoleBindingOwnership(
+	instance *k8sv1beta1.Space,
+	organization *k8sv1beta1.Organization,
+	roleBinding *rbac.RoleBinding,
+	ctx context.Context) error {
+	found := &rbac.RoleBinding{}
+	err := r.Get(ctx, client.ObjectKey{Name: roleBinding.Name, Namespace: roleBinding.Namespace}, found)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	labels := found.GetLabels()
+	if labels[labelOrganization] != organization.Name || labels[labelSpace] != instance.Name {
+		return fmt.Errorf("RoleBinding %s/%s already exists and is not managed by the Space",
+			found.Namespace, found.Name)
+	}
+	return nil
+}
+
+// deleteStaleRoleBindings removes the RoleBindings created on behalf of the
+// Space that are no longer wanted, like the ones removed from a
+// SpaceExtraConfig
+func (r *SpaceReconciler) deleteStaleRoleBindings(
+	instance *k8sv1beta1.Space,
+	organization *k8sv1beta1.Organization,
+	namespace string,
+	wanted []*rbac.RoleBinding,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	wantedNames := map[string]bool{}
+	for _, roleBinding := range wanted {
+		wantedNames[roleBinding.Name] = true
+	}
+
+	roleBindings := &rbac.RoleBindingList{}
+	err := r.List(ctx, roleBindings,
+		client.InNamespace(namespace),
+		client.MatchingLabels(labelsOfSpaceObjects(instance, organization)))
+	if err != nil {
+		return err
+	}
+
+	for i := range roleBindings.Items {
+		roleBinding := &roleBindings.Items[i]
+		if wantedNames[roleBinding.Name] {
+			continue
+		}
+		reqLogger.Info("Deleting RoleBinding no longer wanted",
+			"Namespace", roleBinding.Namespace,
+			"RoleBinding", roleBinding.Name)
+		if err = r.Delete(ctx, roleBinding); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonDeleted,
+			"Deleted RoleBinding %s/%s", roleBinding.Namespace, roleBinding.Name)
+		observeDeletion(organization.Name, "RoleBinding")
+	}
+
+	return nil
+}
+
+// resourceQuotaOfSpace returns the spec of the ResourceQuota of the Space:
+// the quota defined by the Space merged with the one coming from the
+// SpaceExtraConfig objects, which takes precedence. nil is returned when no
+// quota has to be enforced.
+func resourceQuotaOfSpace(space *k8sv1beta1.Space, extraConfig *k8sv1beta1.SpaceExtraConfigSpec) *corev1.ResourceQuotaSpec {
+	var spec *corev1.ResourceQuotaSpec
+	if space.Spec.Quota != nil {
+		spec = space.Spec.Quota.ResourceQuotaSpec()
+	}
+	if extraConfig.ResourceQuota != nil {
+		spec = k8sv1beta1.MergeResourceQuotaSpecs(spec, extraConfig.ResourceQuota)
+	}
+	return spec
+}
+
+// reconcileResourceQuota creates or updates the ResourceQuota of the Space,
+// the ResourceQuota is removed when spec is nil
+func (r *SpaceReconciler) reconcileResourceQuota(
+	instance *k8sv1beta1.Space,
+	organization *k8sv1beta1.Organization,
+	namespace string,
+	spec *corev1.ResourceQuotaSpec,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	if spec == nil {
+		deleted, err := common.DeleteManagedObject(
+			r, &corev1.ResourceQuota{}, namespace, resourceQuotaName,
+			labelsOfSpaceObjects(instance, organization), reqLogger, ctx)
+		if deleted {
+			r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonDeleted,
+				"Deleted ResourceQuota %s/%s", namespace, resourceQuotaName)
+			observeDeletion(organization.Name, "ResourceQuota")
+		}
+		return err
+	}
+
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resourceQuotaName,
+			Namespace: namespace,
+			Labels:    labelsOfSpaceObjects(instance, organization),
 		},
-	)
+		Spec: *spec,
+	}
 	reqLogger.Info(
-		"Reconciling RoleBinding",
-		"Namespace", namespaceCR.Name,
-		"RoleBinding", roleBinding.Name)
-	roleBinding.ObjectMeta.SetLabels(roleBindingLabels)
-	if err = common.ReconcileRBACRoleBinding(r, roleBinding, nil, nil, reqLogger, ctx); err != nil {
-		return ctrl.Result{}, err
+		"Reconciling ResourceQuota",
+		"Namespace", namespace,
+		"ResourceQuota", quota.Name)
+	result, err := common.ReconcileResourceQuota(r, quota, nil, nil, reqLogger, ctx)
+	if err != nil {
+		return err
+	}
+	recordOperation(r.Recorder, instance, organization.Name, "ResourceQuota", namespace, quota.Name, result)
+	return nil
+}
+
+// limitRangeOfSpace returns the spec of the LimitRange of the Space: the
+// container limits of the Organization, overridden by the ones of the Space,
+// followed by the limits coming from the SpaceExtraConfig objects. nil is
+// returned when no limits have to be enforced.
+func limitRangeOfSpace(
+	space *k8sv1beta1.Space,
+	organization *k8sv1beta1.Organization,
+	extraConfig *k8sv1beta1.SpaceExtraConfigSpec) *corev1.LimitRangeSpec {
+	var spec *corev1.LimitRangeSpec
+	containerLimits := k8sv1beta1.MergeContainerLimits(
+		organization.Spec.DefaultContainerLimits,
+		space.Spec.ContainerLimits)
+	if containerLimits != nil {
+		spec = &corev1.LimitRangeSpec{
+			Limits: []corev1.LimitRangeItem{containerLimits.LimitRangeItem()},
+		}
+	}
+	if extraConfig.LimitRange != nil {
+		if spec == nil {
+			spec = &corev1.LimitRangeSpec{}
+		}
+		for _, limit := range extraConfig.LimitRange.Limits {
+			spec.Limits = append(spec.Limits, *limit.DeepCopy())
+		}
+	}
+	return spec
+}
+
+// reconcileLimitRange creates or updates the LimitRange of the Space, the
+// LimitRange is removed when spec is nil
+func (r *SpaceReconciler) reconcileLimitRange(
+	instance *k8sv1beta1.Space,
+	organization *k8sv1beta1.Organization,
+	namespace string,
+	spec *corev1.LimitRangeSpec,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	if spec == nil {
+		deleted, err := common.DeleteManagedObject(
+			r, &corev1.LimitRange{}, namespace, limitRangeName,
+			labelsOfSpaceObjects(instance, organization), reqLogger, ctx)
+		if deleted {
+			r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonDeleted,
+				"Deleted LimitRange %s/%s", namespace, limitRangeName)
+			observeDeletion(organization.Name, "LimitRange")
+		}
+		return err
 	}
 
-	// Create a RoleBinding for editor groups and users
-	roleBinding = common.NewRoleBinding(
-		"editors",
-		namespaceCR.Name,
-		instance.Spec.Editors,
-		append(organization.Spec.EditorGroups, instance.Spec.EditorGroups...),
-		rbac.RoleRef{
-			APIGroup: "rbac.authorization.k8s.io",
-			Kind:     "ClusterRole",
-			Name:     "edit",
+	limitRange := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      limitRangeName,
+			Namespace: namespace,
+			Labels:    labelsOfSpaceObjects(instance, organization),
 		},
-	)
-	roleBinding.ObjectMeta.SetLabels(roleBindingLabels)
+		Spec: *spec,
+	}
 	reqLogger.Info(
-		"Reconciling RoleBinding",
-		"Namespace", namespaceCR.Name,
-		"RoleBinding", roleBinding.Name)
-	if err = common.ReconcileRBACRoleBinding(r, roleBinding, nil, nil, reqLogger, ctx); err != nil {
-		return ctrl.Result{}, err
+		"Reconciling LimitRange",
+		"Namespace", namespace,
+		"LimitRange", limitRange.Name)
+	result, err := common.ReconcileLimitRange(r, limitRange, nil, nil, reqLogger, ctx)
+	if err != nil {
+		return err
+	}
+	recordOperation(r.Recorder, instance, organization.Name, "LimitRange", namespace, limitRange.Name, result)
+	return nil
+}
+
+// reconcileNetworkPolicy creates or updates the NetworkPolicy enforcing the
+// network isolation of the Space, the NetworkPolicy is removed when no
+// isolation is required
+func (r *SpaceReconciler) reconcileNetworkPolicy(
+	instance *k8sv1beta1.Space,
+	organization *k8sv1beta1.Organization,
+	namespace string,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	networkPolicy := networkPolicyAssociatedWithSpace(instance, organization, namespace)
+	if networkPolicy == nil {
+		deleted, err := common.DeleteManagedObject(
+			r, &networking.NetworkPolicy{}, namespace, networkPolicyName,
+			labelsOfSpaceObjects(instance, organization), reqLogger, ctx)
+		if deleted {
+			r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonDeleted,
+				"Deleted NetworkPolicy %s/%s", namespace, networkPolicyName)
+			observeDeletion(organization.Name, "NetworkPolicy")
+		}
+		return err
 	}
 
-	// Create a RoleBinding for viewer groups and users
-	roleBinding = common.NewRoleBinding(
-		"viewers",
-		namespaceCR.Name,
-		instance.Spec.Viewers,
-		append(organization.Spec.ViewerGroups, instance.Spec.ViewerGroups...),
-		rbac.RoleRef{
-			APIGroup: "rbac.authorization.k8s.io",
-			Kind:     "ClusterRole",
-			Name:     "view",
-		},
-	)
-	roleBinding.ObjectMeta.SetLabels(roleBindingLabels)
 	reqLogger.Info(
-		"Reconciling RoleBinding",
-		"Namespace", namespaceCR.Name,
-		"RoleBinding", roleBinding.Name)
-	if err = common.ReconcileRBACRoleBinding(r, roleBinding, nil, nil, reqLogger, ctx); err != nil {
-		return ctrl.Result{}, err
+		"Reconciling NetworkPolicy",
+		"Namespace", namespace,
+		"NetworkPolicy", networkPolicy.Name)
+	result, err := common.ReconcileNetworkPolicy(r, networkPolicy, nil, nil, reqLogger, ctx)
+	if err != nil {
+		return err
 	}
+	recordOperation(r.Recorder, instance, organization.Name, "NetworkPolicy", namespace, networkPolicy.Name, result)
+	return nil
+}
 
-	return ctrl.Result{}, nil
+// updateStatus computes the phase of the Space and writes its status
+// through the status subresource
+func (r *SpaceReconciler) updateStatus(
+	instance *k8sv1beta1.Space,
+	reconcileErr error,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	instance.Status.ObservedGeneration = instance.Generation
+
+	if reconcileErr != nil {
+		instance.Status.Phase = k8sv1beta1.SpacePhaseError
+		instance.Status.LastReconcileError = reconcileErr.Error()
+	} else {
+		instance.Status.LastReconcileError = ""
+		if instance.GetDeletionTimestamp() != nil {
+			instance.Status.Phase = k8sv1beta1.SpacePhaseTerminating
+		} else if k8sv1beta1.IsConditionTrue(instance.Status.Conditions, k8sv1beta1.SpaceConditionOrganizationFound) &&
+			k8sv1beta1.IsConditionTrue(instance.Status.Conditions, k8sv1beta1.SpaceConditionNamespaceReady) &&
+			k8sv1beta1.IsConditionTrue(instance.Status.Conditions, k8sv1beta1.SpaceConditionRBACReady) &&
+			k8sv1beta1.IsConditionTrue(instance.Status.Conditions, k8sv1beta1.SpaceConditionQuotaReady) &&
+			k8sv1beta1.IsConditionTrue(instance.Status.Conditions, k8sv1beta1.SpaceConditionLimitRangeReady) &&
+			k8sv1beta1.IsConditionTrue(instance.Status.Conditions, k8sv1beta1.SpaceConditionNetworkPolicyReady) {
+			instance.Status.Phase = k8sv1beta1.SpacePhaseReady
+		} else {
+			instance.Status.Phase = k8sv1beta1.SpacePhasePending
+		}
+	}
+
+	if err := r.Status().Update(ctx, instance); err != nil {
+		reqLogger.Error(err, "Cannot update status of Space")
+		return err
+	}
+	return nil
+}
+
+func setSpaceCondition(instance *k8sv1beta1.Space, conditionType string, status corev1.ConditionStatus, reason, message string) {
+	k8sv1beta1.SetCondition(&instance.Status.Conditions, k8sv1beta1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: instance.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
 }
 
-func (r *SpaceReconciler) organizationOwningSpace(orgName string, reqLogger logr.Logger, ctx context.Context) (*k8sv1alpha1.Organization, error) {
+func (r *SpaceReconciler) organizationOwningSpace(orgName string, reqLogger logr.Logger, ctx context.Context) (*k8sv1beta1.Organization, error) {
 	reqLogger.Info(
 		"Searching for organization owning space",
 		"Organization.Name", orgName)
-	organization := &k8sv1alpha1.Organization{}
+	organization := &k8sv1beta1.Organization{}
 	err := r.Get(
 		ctx,
 		client.ObjectKey{
@@ -189,7 +574,7 @@ func (r *SpaceReconciler) organizationOwningSpace(orgName string, reqLogger logr
 	return organization, err
 }
 
-func (r *SpaceReconciler) handleFinalizer(instance *k8sv1alpha1.Space, organization *k8sv1alpha1.Organization, reqLogger logr.Logger, ctx context.Context) (ctrl.Result, error) {
+func (r *SpaceReconciler) handleFinalizer(instance *k8sv1beta1.Space, organizationName string, reqLogger logr.Logger, ctx context.Context) (ctrl.Result, error) {
 	finalizerFound := false
 	newFinalizers := []string{}
 	for _, finalizer := range instance.GetFinalizers() {
@@ -207,18 +592,66 @@ func (r *SpaceReconciler) handleFinalizer(instance *k8sv1alpha1.Space, organizat
 		// the resource.
 		reqLogger.Info("Handling finalizer")
 
-		namespace := namespaceAssociatedWithSpace(instance, organization)
-		err := r.Delete(ctx, namespace)
+		namespaces, err := r.namespacesOfSpace(instance, organizationName, ctx)
 		if err != nil {
-			if !errors.IsNotFound(err) {
-				return ctrl.Result{}, err
-			} else {
-				reqLogger.Info("Cannot find Namespace associated with Space",
+			return ctrl.Result{}, err
+		}
+		if len(namespaces) == 0 {
+			reqLogger.Info("Cannot find Namespace associated with Space")
+		}
+		terminating := []*corev1.Namespace{}
+		for i := range namespaces {
+			namespace := &namespaces[i]
+			if namespace.GetDeletionTimestamp() != nil {
+				terminating = append(terminating, namespace)
+				continue
+			}
+			switch instance.Spec.DeletionPolicy {
+			case k8sv1beta1.SpaceDeletionPolicyOrphan:
+				reqLogger.Info("Leaving Namespace related with Space untouched",
+					"Namespace.Name", namespace.Name)
+				r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonNamespaceOrphaned,
+					"Left Namespace %s untouched", namespace.Name)
+			case k8sv1beta1.SpaceDeletionPolicyRetain:
+				if err = r.retainNamespace(instance, organizationName, namespace, reqLogger, ctx); err != nil {
+					return ctrl.Result{}, err
+				}
+				r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonNamespaceRetained,
+					"Retained Namespace %s, removed the objects and the labels of the operator", namespace.Name)
+			default:
+				err = r.Delete(ctx, namespace)
+				if err != nil {
+					if !errors.IsNotFound(err) {
+						return ctrl.Result{}, err
+					}
+					continue
+				}
+				reqLogger.Info("Deleted Namespace related with Space",
 					"Namespace.Name", namespace.Name)
+				r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonDeleted,
+					"Deleted Namespace %s", namespace.Name)
+				observeDeletion(organizationName, "Namespace")
+				terminating = append(terminating, namespace)
 			}
-		} else {
-			reqLogger.Info("Deleted Namespace related with Space",
-				"Namespace.Name", namespace.Name)
+		}
+
+		// With the Delete policy the Space is kept around until its
+		// Namespace is really gone, otherwise a new Space with the same
+		// name would race against the terminating Namespace
+		if !retainsNamespace(instance) && len(terminating) > 0 {
+			reportTerminatingNamespaces(instance, terminating)
+			if err = r.updateStatus(instance, nil, reqLogger, ctx); err != nil {
+				return ctrl.Result{}, err
+			}
+			delay := namespaceTerminationBackoff(instance)
+			reqLogger.Info("Waiting for the Namespace of the Space to be terminated",
+				"BlockingFinalizers", instance.Status.NamespaceFinalizers,
+				"RequeueAfter", delay)
+			return ctrl.Result{RequeueAfter: delay}, nil
+		}
+		instance.Status.NamespaceFinalizers = nil
+		if err = r.updateStatus(instance, nil, reqLogger, ctx); err != nil {
+			return ctrl.Result{}, err
 		}
 
 		instance.SetFinalizers(newFinalizers)
@@ -226,13 +659,176 @@ func (r *SpaceReconciler) handleFinalizer(instance *k8sv1alpha1.Space, organizat
 		if err != nil {
 			return ctrl.Result{}, err
 		}
+	} else if err := r.updateStatus(instance, nil, reqLogger, ctx); err != nil {
+		return ctrl.Result{}, err
 	}
 	return ctrl.Result{}, nil
 }
 
+// retainsNamespace returns true when the Namespace of the Space is kept
+// after the Space is deleted
+func retainsNamespace(instance *k8sv1beta1.Space) bool {
+	return instance.Spec.DeletionPolicy == k8sv1beta1.SpaceDeletionPolicyRetain ||
+		instance.Spec.DeletionPolicy == k8sv1beta1.SpaceDeletionPolicyOrphan
+}
+
+// reportTerminatingNamespaces records inside of the status of the Space the
+// finalizers blocking the termination of its Namespaces
+func reportTerminatingNamespaces(instance *k8sv1beta1.Space, namespaces []*corev1.Namespace) {
+	finalizers := []string{}
+	messages := []string{}
+	for _, namespace := range namespaces {
+		for _, finalizer := range namespace.Spec.Finalizers {
+			finalizers = appendUnique(finalizers, string(finalizer))
+		}
+		for _, finalizer := range namespace.GetFinalizers() {
+			finalizers = appendUnique(finalizers, finalizer)
+		}
+		for _, condition := range namespace.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case corev1.NamespaceFinalizersRemaining, corev1.NamespaceContentRemaining:
+				messages = append(messages, fmt.Sprintf("%s: %s", namespace.Name, condition.Message))
+			}
+		}
+	}
+	sort.Strings(finalizers)
+	instance.Status.NamespaceFinalizers = finalizers
+
+	message := fmt.Sprintf("Waiting for the termination of Namespace %s", namespaces[0].Name)
+	if len(messages) > 0 {
+		message = strings.Join(messages, "; ")
+	}
+	setSpaceCondition(instance, k8sv1beta1.SpaceConditionNamespaceReady,
+		corev1.ConditionFalse, "NamespaceTerminating", message)
+}
+
+// namespaceTerminationBackoff returns how long to wait before checking again
+// the termination of the Namespace of the Space. The delay grows with the time
+// spent by the Space in the Terminating phase.
+func namespaceTerminationBackoff(instance *k8sv1beta1.Space) time.Duration {
+	delay := time.Since(instance.GetDeletionTimestamp().Time)
+	if delay < minNamespaceTerminationBackoff {
+		return minNamespaceTerminationBackoff
+	}
+	if delay > maxNamespaceTerminationBackoff {
+		return maxNamespaceTerminationBackoff
+	}
+	return delay.Round(time.Second)
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, existing := range list {
+		if existing == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
+
+// retainNamespace removes from the Namespace of the Space the RoleBindings,
+// the ResourceQuota, the LimitRange, the NetworkPolicy and the labels set by
+// the operator, leaving the workloads untouched
+func (r *SpaceReconciler) retainNamespace(
+	instance *k8sv1beta1.Space,
+	organizationName string,
+	namespace *corev1.Namespace,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	spaceLabels := map[string]string{
+		labelOrganization: organizationName,
+		labelSpace:        instance.Name,
+	}
+
+	roleBindings := &rbac.RoleBindingList{}
+	err := r.List(ctx, roleBindings,
+		client.InNamespace(namespace.Name),
+		client.MatchingLabels(spaceLabels))
+	if err != nil {
+		return err
+	}
+	for i := range roleBindings.Items {
+		roleBinding := &roleBindings.Items[i]
+		reqLogger.Info("Deleting RoleBinding of retained Namespace",
+			"Namespace", roleBinding.Namespace,
+			"RoleBinding", roleBinding.Name)
+		if err = r.Delete(ctx, roleBinding); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		observeDeletion(organizationName, "RoleBinding")
+	}
+
+	managedObjects := []struct {
+		kind string
+		obj  runtime.Object
+		name string
+	}{
+		{"ResourceQuota", &corev1.ResourceQuota{}, resourceQuotaName},
+		{"LimitRange", &corev1.LimitRange{}, limitRangeName},
+		{"NetworkPolicy", &networking.NetworkPolicy{}, networkPolicyName},
+	}
+	for _, managed := range managedObjects {
+		deleted, err := common.DeleteManagedObject(
+			r, managed.obj, namespace.Name, managed.name, spaceLabels, reqLogger, ctx)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			observeDeletion(organizationName, managed.kind)
+		}
+	}
+
+	return common.ReleaseNamespace(r, namespace, []string{labelOrganization, labelSpace}, reqLogger, ctx)
+}
+
+// namespacesOfSpace returns the Namespaces created on behalf of the Space.
+// They are found using the labels set by the operator and, in case these
+// labels have been removed by someone else, using the name of the Namespace
+// of the Space.
+func (r *SpaceReconciler) namespacesOfSpace(instance *k8sv1beta1.Space, organizationName string, ctx context.Context) ([]corev1.Namespace, error) {
+	namespaces := &corev1.NamespaceList{}
+	err := r.List(ctx, namespaces, client.MatchingLabels{
+		labelOrganization: organizationName,
+		labelSpace:        instance.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	for _, namespace := range namespaces.Items {
+		found[namespace.Name] = true
+	}
+	for _, name := range []string{instance.Status.Namespace, instance.NamespaceName(organizationName)} {
+		if name == "" || found[name] {
+			continue
+		}
+		found[name] = true
+
+		namespace := corev1.Namespace{}
+		err = r.Get(ctx, client.ObjectKey{Name: name}, &namespace)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		// Never pick a Namespace that belongs to someone else, or that
+		// the operator never managed
+		if _, labelled := namespace.GetLabels()[labelSpace]; labelled ||
+			!common.IsManagedLabel(&namespace, labelSpace) {
+			continue
+		}
+		namespaces.Items = append(namespaces.Items, namespace)
+	}
+	return namespaces.Items, nil
+}
+
 func (r *SpaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	builder := ctrl.NewControllerManagedBy(mgr)
-	builder = builder.For(&k8sv1alpha1.Space{})
+	builder = builder.For(&k8sv1beta1.Space{})
 
 	// Watch for changes to the namespace related with the resource Space
 	// Note well: we cannot leverage an ownership relation because Space
@@ -240,71 +836,315 @@ func (r *SpaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	builder = builder.Watches(
 		&source.Kind{Type: &corev1.Namespace{}},
 		&handler.EnqueueRequestsFromMapFunc{
-			ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []ctrl.Request {
-				labels := a.Meta.GetLabels()
-				space, foundSpace := labels[labelSpace]
-				org, foundOrg := labels[labelOrganization]
-
-				requests := []ctrl.Request{}
-				if foundSpace && foundOrg {
-					requests = []ctrl.Request{
-						{
-							NamespacedName: client.ObjectKey{
-								Name:      space,
-								Namespace: common.ComputeSpacesNamespaceFromOrganizationName(org),
-							},
-						},
-					}
-				}
-				return requests
-			},
-			),
+			ToRequests: handler.ToRequestsFunc(spaceRequestsFromLabels),
 		},
 	)
 
-	// Watch for changes to the RoleBinding objects created inside of the
-	// Namespace created by the Space resource.
+	// Watch for changes to the RoleBinding, ResourceQuota, LimitRange and
+	// NetworkPolicy objects created inside of the Namespace created by the Space resource.
 	// Note well: we cannot leverage an ownership relation because the Space
-	// object and the RoleBinding ones are not under the same Namespace.
+	// object and these objects are not under the same Namespace.
 	// "Cross namespace ownership relations" are not supported.
+	for _, object := range []runtime.Object{&rbac.RoleBinding{}, &corev1.ResourceQuota{}, &corev1.LimitRange{}, &networking.NetworkPolicy{}} {
+		builder = builder.Watches(
+			&source.Kind{Type: object},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(spaceRequestsFromLabels),
+			},
+		)
+	}
+
+	// Watch for changes to the SpaceExtraConfig objects, their configuration
+	// is merged into the resources created for each Space they apply to
 	builder = builder.Watches(
-		&source.Kind{Type: &rbac.RoleBinding{}},
+		&source.Kind{Type: &k8sv1beta1.SpaceExtraConfig{}},
 		&handler.EnqueueRequestsFromMapFunc{
 			ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []ctrl.Request {
-				labels := a.Meta.GetLabels()
-				space, foundSpace := labels[labelSpace]
-				org, foundOrg := labels[labelOrganization]
-
-				requests := []ctrl.Request{}
-				if foundSpace && foundOrg {
-					requests = []ctrl.Request{
-						{
-							NamespacedName: client.ObjectKey{
-								Name:      space,
-								Namespace: common.ComputeSpacesNamespaceFromOrganizationName(org),
-							},
-						},
-					}
+				extraConfig, ok := a.Object.(*k8sv1beta1.SpaceExtraConfig)
+				if !ok {
+					return []ctrl.Request{}
 				}
-				return requests
-			},
-			),
+				return r.spacesOfExtraConfig(extraConfig)
+			}),
 		},
 	)
 
-	return builder.Complete(r)
+	// Watch for changes to the Organization objects: the RoleBindings and the
+	// Namespace labels of a Space are built using the groups and the labels
+	// defined by the Organization owning it. Each time an Organization
+	// changes, all its Spaces have to be reconciled.
+	// Only the changes to the spec of the Organization matter: its status
+	// is updated each time one of its Spaces changes, reacting to these
+	// updates would reconcile all the Spaces over and over.
+	c, err := builder.Build(r)
+	if err != nil {
+		return err
+	}
+	return c.Watch(
+		&source.Kind{Type: &k8sv1beta1.Organization{}},
+		&handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []ctrl.Request {
+				return r.spacesOfOrganization(a.Meta.GetName())
+			}),
+		},
+		predicate.GenerationChangedPredicate{},
+	)
+}
+
+// spaceRequestsFromLabels maps an object created on behalf of a Space to the
+// reconcile request of the Space, using the labels set by the operator
+func spaceRequestsFromLabels(a handler.MapObject) []ctrl.Request {
+	labels := a.Meta.GetLabels()
+	space, foundSpace := labels[labelSpace]
+	org, foundOrg := labels[labelOrganization]
+
+	requests := []ctrl.Request{}
+	if foundSpace && foundOrg {
+		requests = []ctrl.Request{
+			{
+				NamespacedName: client.ObjectKey{
+					Name:      space,
+					Namespace: common.ComputeSpacesNamespaceFromOrganizationName(org),
+				},
+			},
+		}
+	}
+	return requests
+}
+
+// spacesOfOrganization returns a reconcile request for each one of the
+// Space objects owned by the given Organization
+func (r *SpaceReconciler) spacesOfOrganization(organizationName string) []ctrl.Request {
+	spacesNamespace := common.ComputeSpacesNamespaceFromOrganizationName(organizationName)
+
+	spaces := &k8sv1beta1.SpaceList{}
+	if err := r.List(context.Background(), spaces, client.InNamespace(spacesNamespace)); err != nil {
+		r.Log.Error(err, "Cannot list Spaces of Organization",
+			"Organization.Name", organizationName,
+			"Namespace", spacesNamespace)
+		return []ctrl.Request{}
+	}
+
+	requests := []ctrl.Request{}
+	for _, space := range spaces.Items {
+		requests = append(requests, ctrl.Request{
+			NamespacedName: client.ObjectKey{
+				Name:      space.Name,
+				Namespace: space.Namespace,
+			},
+		})
+	}
+	return requests
 }
 
-func namespaceAssociatedWithSpace(space *k8sv1alpha1.Space, organization *k8sv1alpha1.Organization) *corev1.Namespace {
-	name := common.NameOfNamespaceCreateBySpace(organization.Name, space.Name)
-	labels := organization.Spec.DefaultNamespaceLabels
-	labels[labelOrganization] = organization.Name
-	labels[labelSpace] = space.Name
+// namespaceAssociatedWithSpace returns the Namespace created for the Space.
+// When the same label or annotation is defined in multiple places the
+// following precedence applies, from the lowest to the highest: the defaults
+// of the Organization, the Space, the SpaceExtraConfig objects. The labels
+// identifying the Space cannot be overridden.
+func namespaceAssociatedWithSpace(
+	space *k8sv1beta1.Space,
+	organization *k8sv1beta1.Organization,
+	extraConfig *k8sv1beta1.SpaceExtraConfigSpec) *corev1.Namespace {
+	name := space.NamespaceName(organization.Name)
+
+	// Build new maps: neither the Organization nor the Space must be
+	// altered and their maps could be nil
+	labelSources := []map[string]string{
+		organization.Spec.DefaultNamespaceLabels,
+		space.Spec.NamespaceLabels,
+	}
+	annotationSources := []map[string]string{
+		organization.Spec.DefaultNamespaceAnnotations,
+		space.Spec.NamespaceAnnotations,
+	}
+	if extraConfig != nil {
+		labelSources = append(labelSources, extraConfig.NamespaceLabels)
+		annotationSources = append(annotationSources, extraConfig.NamespaceAnnotations)
+	}
+	labelSources = append(labelSources, labelsOfSpaceObjects(space, organization))
 
 	return &corev1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   name,
-			Labels: labels,
+			Name:        name,
+			Labels:      mergeMaps(labelSources...),
+			Annotations: mergeMaps(annotationSources...),
+		},
+	}
+}
+
+// mergeMaps returns a new map made of all the given ones, the entries of the
+// latter maps override the ones of the former
+func mergeMaps(maps ...map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, m := range maps {
+		for key, value := range m {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// labelsOfSpaceObjects returns the labels identifying the objects created on
+// behalf of the Space
+func labelsOfSpaceObjects(space *k8sv1beta1.Space, organization *k8sv1beta1.Organization) map[string]string {
+	return map[string]string{
+		labelOrganization: organization.Name,
+		labelSpace:        space.Name,
+	}
+}
+
+// roleBindingsAssociatedWithSpace returns the RoleBinding objects that grant
+// admin, edit and view rights inside of the Namespace of the Space. The
+// ClusterRoles of the Organization take precedence over the given ones.
+func roleBindingsAssociatedWithSpace(
+	space *k8sv1beta1.Space,
+	organization *k8sv1beta1.Organization,
+	namespace string,
+	clusterRoles k8sv1beta1.ClusterRoleMapping) []*rbac.RoleBinding {
+	clusterRoles = clusterRoles.Override(organization.Spec.ClusterRoles)
+	roleBindings := []*rbac.RoleBinding{
+		// RoleBinding for admin groups, users and ServiceAccounts
+		common.NewRoleBinding(
+			"administrators",
+			namespace,
+			common.MergeMembers(organization.Spec.Admins, space.Spec.Admins),
+			common.MergeMembers(organization.Spec.AdminGroups, space.Spec.AdminGroups),
+			common.MergeMembers(organization.Spec.AdminServiceAccounts, space.Spec.AdminServiceAccounts),
+			rbac.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     "ClusterRole",
+				Name:     clusterRoles.Admin,
+			},
+		),
+		// RoleBinding for editor groups, users and ServiceAccounts
+		common.NewRoleBinding(
+			"editors",
+			namespace,
+			common.MergeMembers(organization.Spec.Editors, space.Spec.Editors),
+			common.MergeMembers(organization.Spec.EditorGroups, space.Spec.EditorGroups),
+			common.MergeMembers(organization.Spec.EditorServiceAccounts, space.Spec.EditorServiceAccounts),
+			rbac.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     "ClusterRole",
+				Name:     clusterRoles.Edit,
+			},
+		),
+		// RoleBinding for viewer groups, users and ServiceAccounts
+		common.NewRoleBinding(
+			"viewers",
+			namespace,
+			common.MergeMembers(organization.Spec.Viewers, space.Spec.Viewers),
+			common.MergeMembers(organization.Spec.ViewerGroups, space.Spec.ViewerGroups),
+			common.MergeMembers(organization.Spec.ViewerServiceAccounts, space.Spec.ViewerServiceAccounts),
+			rbac.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     "ClusterRole",
+				Name:     clusterRoles.View,
+			},
+		),
+	}
+
+	for _, roleBinding := range roleBindings {
+		roleBinding.ObjectMeta.SetLabels(labelsOfSpaceObjects(space, organization))
+	}
+
+	return roleBindings
+}
+
+// networkPolicyAssociatedWithSpace returns the NetworkPolicy enforcing the
+// network isolation of the Space, nil when no isolation is required
+func networkPolicyAssociatedWithSpace(space *k8sv1beta1.Space, organization *k8sv1beta1.Organization, namespace string) *networking.NetworkPolicy {
+	isolation := space.Spec.NetworkIsolation
+	if isolation == "" {
+		isolation = organization.Spec.NetworkIsolation
+	}
+
+	var peer networking.NetworkPolicyPeer
+	switch isolation {
+	case k8sv1beta1.NetworkIsolationSpace:
+		// All the pods of the same Namespace
+		peer = networking.NetworkPolicyPeer{
+			PodSelector: &metav1.LabelSelector{},
+		}
+	case k8sv1beta1.NetworkIsolationOrganization:
+		// All the pods of the Namespaces belonging to the Organization
+		peer = networking.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					labelOrganization: organization.Name,
+				},
+			},
+		}
+	default:
+		return nil
+	}
+
+	return &networking.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      networkPolicyName,
+			Namespace: namespace,
+			Labels:    labelsOfSpaceObjects(space, organization),
 		},
+		Spec: networking.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networking.PolicyType{networking.PolicyTypeIngress},
+			Ingress: []networking.NetworkPolicyIngressRule{
+				{
+					From: []networking.NetworkPolicyPeer{peer},
+				},
+			},
+		},
+	}
+}
+
+// customRoleBindingsOfSpace returns the RoleBinding objects defined by the
+// roleBindings field of the Space
+func customRoleBindingsOfSpace(
+	space *k8sv1beta1.Space,
+	organization *k8sv1beta1.Organization,
+	namespace string,
+	reqLogger logr.Logger) []*rbac.RoleBinding {
+	roleBindings := []*rbac.RoleBinding{}
+	for _, custom := range space.Spec.RoleBindings {
+		if common.IsBuiltinRoleBindingName(custom.Name) {
+			reqLogger.Info("Ignoring RoleBinding using a name reserved by the operator",
+				"Namespace", namespace,
+				"RoleBinding", custom.Name)
+			continue
+		}
+		roleBinding := common.NewRoleBinding(
+			custom.Name,
+			namespace,
+			common.MergeMembers(custom.Users),
+			common.MergeMembers(custom.Groups),
+			common.MergeMembers(custom.ServiceAccounts),
+			rbac.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     "ClusterRole",
+				Name:     custom.ClusterRole,
+			},
+		)
+		roleBinding.ObjectMeta.SetLabels(labelsOfSpaceObjects(space, organization))
+		roleBindings = append(roleBindings, roleBinding)
+	}
+	return roleBindings
+}
+
+// mergeRoleBindings joins the given lists of RoleBinding objects. When two
+// RoleBindings have the same name the one coming from the latter list wins.
+func mergeRoleBindings(lists ...[]*rbac.RoleBinding) []*rbac.RoleBinding {
+	merged := []*rbac.RoleBinding{}
+	index := map[string]int{}
+	for _, list := range lists {
+		for _, roleBinding := range list {
+			if i, found := index[roleBinding.Name]; found {
+				merged[i] = roleBinding
+				continue
+			}
+			index[roleBinding.Name] = len(merged)
+			merged = append(merged, roleBinding)
+		}
 	}
+	return merged
 }
@@ -0,0 +1,1060 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	rbac "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	k8sv1beta1 "github.com/flavio/organization-operator/api/v1beta1"
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+func TestSpacesOfOrganization(t *testing.T) {
+	c := newFakeCluster(
+		newOrganization("acme"),
+		newSpace("acme", "web"),
+		newSpace("acme", "api"),
+		newSpace("other", "web"),
+	)
+
+	requests := c.spaceReconciler().spacesOfOrganization("acme")
+	names := []string{}
+	for _, request := range requests {
+		if request.Namespace != "acme-spaces" {
+			t.Errorf("unexpected request %v", request)
+		}
+		names = append(names, request.Name)
+	}
+	sort.Strings(names)
+	if expected := []string{"api", "web"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	if requests := c.spaceReconciler().spacesOfOrganization("missing"); len(requests) != 0 {
+		t.Errorf("expected no requests, got %v", requests)
+	}
+}
+
+func TestSpaceRequestsFromLabels(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		expected []ctrl.Request
+	}{
+		{
+			name: "object of a Space",
+			labels: map[string]string{
+				labelOrganization: "acme",
+				labelSpace:        "web",
+			},
+			expected: []ctrl.Request{{
+				NamespacedName: client.ObjectKey{Name: "web", Namespace: "acme-spaces"},
+			}},
+		},
+		{
+			name:     "Organization label only",
+			labels:   map[string]string{labelOrganization: "acme"},
+			expected: []ctrl.Request{},
+		},
+		{
+			name:     "not labelled",
+			labels:   nil,
+			expected: []ctrl.Request{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "acme-web-space",
+				Labels: test.labels,
+			}}
+			requests := spaceRequestsFromLabels(handler.MapObject{Meta: namespace, Object: namespace})
+			if !reflect.DeepEqual(requests, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, requests)
+			}
+		})
+	}
+}
+
+func TestOrganizationChangeReachesSpaces(t *testing.T) {
+	organization := newOrganization("acme")
+	organization.Spec.AdminGroups = []string{"acme-admins"}
+	c := newOrganizationCluster(t, organization, newSpace("acme", "web"))
+	c.reconcileSpace(t, "acme", "web")
+
+	c.get(t, "", "acme", organization)
+	organization.Spec.AdminGroups = []string{"acme-owners"}
+	if err := c.Update(context.Background(), organization); err != nil {
+		t.Fatal(err)
+	}
+	for _, request := range c.spaceReconciler().spacesOfOrganization("acme") {
+		c.reconcileSpace(t, "acme", request.Name)
+	}
+
+	roleBinding := &rbac.RoleBinding{}
+	c.get(t, "acme-web-space", "administrators", roleBinding)
+	expected := []rbac.Subject{{
+		Kind:     rbac.GroupKind,
+		Name:     "acme-owners",
+		APIGroup: rbac.GroupName,
+	}}
+	if !reflect.DeepEqual(roleBinding.Subjects, expected) {
+		t.Errorf("expected subjects %v, got %v", expected, roleBinding.Subjects)
+	}
+}
+
+// assertCondition fails the test when the condition doesn't have the given
+// status and reason
+func assertCondition(t *testing.T, conditions []k8sv1beta1.Condition, conditionType string, status corev1.ConditionStatus, reason string) {
+	t.Helper()
+	condition := k8sv1beta1.FindCondition(conditions, conditionType)
+	if condition == nil {
+		t.Errorf("condition %s not found", conditionType)
+		return
+	}
+	if condition.Status != status || condition.Reason != reason {
+		t.Errorf("expected condition %s to be %s/%s, got %s/%s",
+			conditionType, status, reason, condition.Status, condition.Reason)
+	}
+}
+
+func TestSpaceStatusReady(t *testing.T) {
+	space := newSpace("acme", "web")
+	space.Generation = 3
+	c := newOrganizationCluster(t, newOrganization("acme"), space)
+	c.reconcileSpace(t, "acme", "web")
+
+	c.get(t, "acme-spaces", "web", space)
+	if space.Status.Phase != k8sv1beta1.SpacePhaseReady {
+		t.Errorf("expected phase %s, got %s", k8sv1beta1.SpacePhaseReady, space.Status.Phase)
+	}
+	if space.Status.Namespace != "acme-web-space" {
+		t.Errorf("expected Namespace acme-web-space, got %q", space.Status.Namespace)
+	}
+	if space.Status.ObservedGeneration != 3 {
+		t.Errorf("expected observedGeneration 3, got %d", space.Status.ObservedGeneration)
+	}
+	if space.Status.LastReconcileError != "" {
+		t.Errorf("unexpected reconcile error %q", space.Status.LastReconcileError)
+	}
+	assertCondition(t, space.Status.Conditions, k8sv1beta1.SpaceConditionOrganizationFound,
+		corev1.ConditionTrue, "OrganizationFound")
+	assertCondition(t, space.Status.Conditions, k8sv1beta1.SpaceConditionNamespaceReady,
+		corev1.ConditionTrue, "NamespaceReconciled")
+	assertCondition(t, space.Status.Conditions, k8sv1beta1.SpaceConditionRBACReady,
+		corev1.ConditionTrue, "RoleBindingsReconciled")
+	for _, condition := range space.Status.Conditions {
+		if condition.ObservedGeneration != 3 {
+			t.Errorf("expected condition %s to observe generation 3, got %d",
+				condition.Type, condition.ObservedGeneration)
+		}
+	}
+}
+
+func TestSpaceStatusOrganizationMissing(t *testing.T) {
+	organization := newOrganization("acme")
+	c := newOrganizationCluster(t, organization, newSpace("acme", "web"))
+	// The Namespace holding the Spaces outlives the Organization
+	if err := c.Delete(context.Background(), organization); err != nil {
+		t.Fatal(err)
+	}
+	c.reconcileSpace(t, "acme", "web")
+
+	space := &k8sv1beta1.Space{}
+	c.get(t, "acme-spaces", "web", space)
+	if space.Status.Phase != k8sv1beta1.SpacePhaseError {
+		t.Errorf("expected phase %s, got %s", k8sv1beta1.SpacePhaseError, space.Status.Phase)
+	}
+	if space.Status.LastReconcileError == "" {
+		t.Error("expected the reconcile error to be reported")
+	}
+	assertCondition(t, space.Status.Conditions, k8sv1beta1.SpaceConditionOrganizationFound,
+		corev1.ConditionFalse, "OrganizationNotFound")
+}
+
+func TestSpaceStatusReconcileError(t *testing.T) {
+	// A Namespace with the same name exists and isn't managed by the operator
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "acme-web-space"}}
+	c := newOrganizationCluster(t, newOrganization("acme"), newSpace("acme", "web"), namespace)
+
+	_, err := c.spaceReconciler().Reconcile(ctrl.Request{
+		NamespacedName: client.ObjectKey{Name: "web", Namespace: "acme-spaces"},
+	})
+	if err == nil {
+		t.Fatal("expected the reconciliation to fail")
+	}
+
+	space := &k8sv1beta1.Space{}
+	c.get(t, "acme-spaces", "web", space)
+	if space.Status.Phase != k8sv1beta1.SpacePhaseError {
+		t.Errorf("expected phase %s, got %s", k8sv1beta1.SpacePhaseError, space.Status.Phase)
+	}
+	if space.Status.LastReconcileError != err.Error() {
+		t.Errorf("expected reconcile error %q, got %q", err.Error(), space.Status.LastReconcileError)
+	}
+	assertCondition(t, space.Status.Conditions, k8sv1beta1.SpaceConditionNamespaceReady,
+		corev1.ConditionFalse, "NamespaceNotManaged")
+
+	// The error is cleared once the Namespace can be managed
+	if err = c.Delete(context.Background(), namespace); err != nil {
+		t.Fatal(err)
+	}
+	c.reconcileSpace(t, "acme", "web")
+	space = &k8sv1beta1.Space{}
+	c.get(t, "acme-spaces", "web", space)
+	if space.Status.Phase != k8sv1beta1.SpacePhaseReady || space.Status.LastReconcileError != "" {
+		t.Errorf("expected the Space to recover, got phase %s and error %q",
+			space.Status.Phase, space.Status.LastReconcileError)
+	}
+}
+
+// updateSpace applies change to the Space web of the Organization acme
+func updateSpace(t *testing.T, c *fakeCluster, change func(space *k8sv1beta1.Space)) {
+	t.Helper()
+	space := &k8sv1beta1.Space{}
+	c.get(t, "acme-spaces", "web", space)
+	change(space)
+	if err := c.Update(context.Background(), space); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSpaceResourceQuota(t *testing.T) {
+	hard := corev1.ResourceList{
+		corev1.ResourceRequestsCPU: resource.MustParse("2"),
+		corev1.ResourcePods:        resource.MustParse("10"),
+	}
+	space := newSpace("acme", "web")
+	space.Spec.Quota = &k8sv1beta1.SpaceQuota{
+		Hard:   hard,
+		Scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeNotBestEffort},
+	}
+	c := newOrganizationCluster(t, newOrganization("acme"), space)
+	c.reconcileSpace(t, "acme", "web")
+
+	quota := &corev1.ResourceQuota{}
+	c.get(t, "acme-web-space", resourceQuotaName, quota)
+	if !equality.Semantic.DeepEqual(quota.Spec.Hard, hard) {
+		t.Errorf("expected hard limits %v, got %v", hard, quota.Spec.Hard)
+	}
+	if !reflect.DeepEqual(quota.Spec.Scopes, space.Spec.Quota.Scopes) {
+		t.Errorf("expected scopes %v, got %v", space.Spec.Quota.Scopes, quota.Spec.Scopes)
+	}
+	if !reflect.DeepEqual(quota.GetLabels(), labelsOfSpaceObjects(space, newOrganization("acme"))) {
+		t.Errorf("unexpected labels %v", quota.GetLabels())
+	}
+	c.get(t, "acme-spaces", "web", space)
+	assertCondition(t, space.Status.Conditions, k8sv1beta1.SpaceConditionQuotaReady,
+		corev1.ConditionTrue, "ResourceQuotaReconciled")
+	c.events()
+
+	// Changes made by someone else are reverted
+	quota.Spec.Hard[corev1.ResourcePods] = resource.MustParse("100")
+	if err := c.Update(context.Background(), quota); err != nil {
+		t.Fatal(err)
+	}
+	c.reconcileSpace(t, "acme", "web")
+	quota = &corev1.ResourceQuota{}
+	c.get(t, "acme-web-space", resourceQuotaName, quota)
+	if !equality.Semantic.DeepEqual(quota.Spec.Hard, hard) {
+		t.Errorf("expected hard limits %v, got %v", hard, quota.Spec.Hard)
+	}
+	assertEvent(t, c.events(), "Warning DriftCorrected Reverted changes made by someone else to ResourceQuota acme-web-space/space-quota")
+
+	// The ResourceQuota is removed together with the quota of the Space
+	updateSpace(t, c, func(space *k8sv1beta1.Space) {
+		space.Spec.Quota = nil
+	})
+	c.reconcileSpace(t, "acme", "web")
+	err := c.Get(context.Background(), client.ObjectKey{Name: resourceQuotaName, Namespace: "acme-web-space"}, quota)
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the ResourceQuota to be deleted, got %v", err)
+	}
+	assertEvent(t, c.events(), "Normal Deleted Deleted ResourceQuota acme-web-space/space-quota")
+}
+
+func TestSpaceWithoutResourceQuota(t *testing.T) {
+	// A ResourceQuota with the same name, not created by the operator, is
+	// left untouched when the Space has no quota
+	quota := &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{
+		Name:      resourceQuotaName,
+		Namespace: "acme-web-space",
+	}}
+	c := newOrganizationCluster(t, newOrganization("acme"), newSpace("acme", "web"), quota)
+	c.reconcileSpace(t, "acme", "web")
+
+	c.get(t, "acme-web-space", resourceQuotaName, quota)
+}
+
+func TestLimitRangeOfSpace(t *testing.T) {
+	organizationLimits := &k8sv1beta1.ContainerLimits{
+		DefaultRequest: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		},
+		Max: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+	}
+	spaceLimits := &k8sv1beta1.ContainerLimits{
+		DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")},
+	}
+	podLimit := corev1.LimitRangeItem{
+		Type: corev1.LimitTypePod,
+		Max:  corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+	}
+
+	tests := []struct {
+		name               string
+		organizationLimits *k8sv1beta1.ContainerLimits
+		spaceLimits        *k8sv1beta1.ContainerLimits
+		extraConfig        k8sv1beta1.SpaceExtraConfigSpec
+		expected           *corev1.LimitRangeSpec
+	}{
+		{
+			name:     "no limits",
+			expected: nil,
+		},
+		{
+			name:               "defaults of the Organization",
+			organizationLimits: organizationLimits,
+			expected: &corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{
+				organizationLimits.LimitRangeItem(),
+			}},
+		},
+		{
+			name:               "Space overrides the Organization resource by resource",
+			organizationLimits: organizationLimits,
+			spaceLimits:        spaceLimits,
+			expected: &corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+				Type: corev1.LimitTypeContainer,
+				DefaultRequest: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("200m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				},
+				Max: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+			}}},
+		},
+		{
+			name:        "limits of the SpaceExtraConfig objects are appended",
+			spaceLimits: spaceLimits,
+			extraConfig: k8sv1beta1.SpaceExtraConfigSpec{
+				LimitRange: &corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{podLimit}},
+			},
+			expected: &corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{
+				spaceLimits.LimitRangeItem(),
+				podLimit,
+			}},
+		},
+		{
+			name: "limits of the SpaceExtraConfig objects only",
+			extraConfig: k8sv1beta1.SpaceExtraConfigSpec{
+				LimitRange: &corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{podLimit}},
+			},
+			expected: &corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{podLimit}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			organization := newOrganization("acme")
+			organization.Spec.DefaultContainerLimits = test.organizationLimits
+			space := newSpace("acme", "web")
+			space.Spec.ContainerLimits = test.spaceLimits
+
+			spec := limitRangeOfSpace(space, organization, &test.extraConfig)
+			if !equality.Semantic.DeepEqual(spec, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, spec)
+			}
+		})
+	}
+}
+
+func TestSpaceLimitRange(t *testing.T) {
+	organization := newOrganization("acme")
+	organization.Spec.DefaultContainerLimits = &k8sv1beta1.ContainerLimits{
+		DefaultRequest: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+		Max:            corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+	}
+	c := newOrganizationCluster(t, organization, newSpace("acme", "web"))
+	c.reconcileSpace(t, "acme", "web")
+
+	limitRange := &corev1.LimitRange{}
+	c.get(t, "acme-web-space", limitRangeName, limitRange)
+	if len(limitRange.Spec.Limits) != 1 {
+		t.Fatalf("expected one limit, got %v", limitRange.Spec.Limits)
+	}
+	// Like the API server, the operator defaults the limit to the max
+	if limit := limitRange.Spec.Limits[0].Default[corev1.ResourceCPU]; limit.Cmp(resource.MustParse("2")) != 0 {
+		t.Errorf("expected the default limit to be the max, got %v", limitRange.Spec.Limits[0].Default)
+	}
+	c.events()
+
+	// The defaults applied by the API server are not mistaken for a drift
+	c.reconcileSpace(t, "acme", "web")
+	if events := c.events(); len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+
+	limitRange.Spec.Limits = nil
+	if err := c.Update(context.Background(), limitRange); err != nil {
+		t.Fatal(err)
+	}
+	c.reconcileSpace(t, "acme", "web")
+	assertEvent(t, c.events(), "Warning DriftCorrected Reverted changes made by someone else to LimitRange acme-web-space/space-limits")
+
+	// The LimitRange is removed when no limits apply anymore
+	organization = &k8sv1beta1.Organization{}
+	c.get(t, "", "acme", organization)
+	organization.Spec.DefaultContainerLimits = nil
+	if err := c.Update(context.Background(), organization); err != nil {
+		t.Fatal(err)
+	}
+	c.reconcileSpace(t, "acme", "web")
+	err := c.Get(context.Background(), client.ObjectKey{Name: limitRangeName, Namespace: "acme-web-space"}, limitRange)
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the LimitRange to be deleted, got %v", err)
+	}
+	space := &k8sv1beta1.Space{}
+	c.get(t, "acme-spaces", "web", space)
+	assertCondition(t, space.Status.Conditions, k8sv1beta1.SpaceConditionLimitRangeReady,
+		corev1.ConditionTrue, "LimitRangeReconciled")
+}
+
+func TestNetworkPolicyAssociatedWithSpace(t *testing.T) {
+	sameSpace := networking.NetworkPolicyPeer{
+		PodSelector: &metav1.LabelSelector{},
+	}
+	sameOrganization := networking.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{labelOrganization: "acme"},
+		},
+	}
+
+	tests := []struct {
+		name                  string
+		organizationIsolation k8sv1beta1.NetworkIsolation
+		spaceIsolation        k8sv1beta1.NetworkIsolation
+		expected              *networking.NetworkPolicyPeer
+	}{
+		{"no isolation", "", "", nil},
+		{"none", k8sv1beta1.NetworkIsolationNone, "", nil},
+		{"space isolation of the Organization", k8sv1beta1.NetworkIsolationSpace, "", &sameSpace},
+		{"organization isolation of the Organization", k8sv1beta1.NetworkIsolationOrganization, "", &sameOrganization},
+		{"Space isolation wins", k8sv1beta1.NetworkIsolationOrganization, k8sv1beta1.NetworkIsolationSpace, &sameSpace},
+		{"Space opts out", k8sv1beta1.NetworkIsolationSpace, k8sv1beta1.NetworkIsolationNone, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			organization := newOrganization("acme")
+			organization.Spec.NetworkIsolation = test.organizationIsolation
+			space := newSpace("acme", "web")
+			space.Spec.NetworkIsolation = test.spaceIsolation
+
+			networkPolicy := networkPolicyAssociatedWithSpace(space, organization, "acme-web-space")
+			if test.expected == nil {
+				if networkPolicy != nil {
+					t.Errorf("expected no NetworkPolicy, got %v", networkPolicy)
+				}
+				return
+			}
+			if networkPolicy == nil {
+				t.Fatal("expected a NetworkPolicy")
+			}
+			expected := networking.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{},
+				PolicyTypes: []networking.PolicyType{networking.PolicyTypeIngress},
+				Ingress: []networking.NetworkPolicyIngressRule{{
+					From: []networking.NetworkPolicyPeer{*test.expected},
+				}},
+			}
+			if !reflect.DeepEqual(networkPolicy.Spec, expected) {
+				t.Errorf("expected %v, got %v", expected, networkPolicy.Spec)
+			}
+			if networkPolicy.Name != networkPolicyName || networkPolicy.Namespace != "acme-web-space" {
+				t.Errorf("unexpected NetworkPolicy %s/%s", networkPolicy.Namespace, networkPolicy.Name)
+			}
+			if !reflect.DeepEqual(networkPolicy.GetLabels(), labelsOfSpaceObjects(space, organization)) {
+				t.Errorf("unexpected labels %v", networkPolicy.GetLabels())
+			}
+		})
+	}
+}
+
+func TestSpaceNetworkPolicy(t *testing.T) {
+	organization := newOrganization("acme")
+	organization.Spec.NetworkIsolation = k8sv1beta1.NetworkIsolationOrganization
+	c := newOrganizationCluster(t, organization, newSpace("acme", "web"))
+	c.reconcileSpace(t, "acme", "web")
+
+	networkPolicy := &networking.NetworkPolicy{}
+	c.get(t, "acme-web-space", networkPolicyName, networkPolicy)
+
+	// The Namespace carries the label the NetworkPolicies of the other
+	// Spaces select
+	namespace := &corev1.Namespace{}
+	c.get(t, "", "acme-web-space", namespace)
+	if namespace.GetLabels()[labelOrganization] != "acme" {
+		t.Errorf("expected the Namespace to be labelled with the Organization, got %v", namespace.GetLabels())
+	}
+
+	updateSpace(t, c, func(space *k8sv1beta1.Space) {
+		space.Spec.NetworkIsolation = k8sv1beta1.NetworkIsolationNone
+	})
+	c.reconcileSpace(t, "acme", "web")
+	err := c.Get(context.Background(), client.ObjectKey{Name: networkPolicyName, Namespace: "acme-web-space"}, networkPolicy)
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the NetworkPolicy to be deleted, got %v", err)
+	}
+	space := &k8sv1beta1.Space{}
+	c.get(t, "acme-spaces", "web", space)
+	assertCondition(t, space.Status.Conditions, k8sv1beta1.SpaceConditionNetworkPolicyReady,
+		corev1.ConditionTrue, "NetworkPolicyReconciled")
+}
+
+// roleRefsOf returns the ClusterRole referenced by each RoleBinding
+func roleRefsOf(roleBindings []*rbac.RoleBinding) map[string]string {
+	roleRefs := map[string]string{}
+	for _, roleBinding := range roleBindings {
+		roleRefs[roleBinding.Name] = roleBinding.RoleRef.Name
+	}
+	return roleRefs
+}
+
+func TestRoleBindingsAssociatedWithSpaceClusterRoles(t *testing.T) {
+	clusterRoles := k8sv1beta1.ClusterRoleMapping{Admin: "admin", Edit: "edit", View: "view"}
+
+	tests := []struct {
+		name     string
+		override *k8sv1beta1.ClusterRoleMapping
+		expected map[string]string
+	}{
+		{
+			name:     "operator mapping",
+			override: nil,
+			expected: map[string]string{"administrators": "admin", "editors": "edit", "viewers": "view"},
+		},
+		{
+			name:     "Organization overrides a tier",
+			override: &k8sv1beta1.ClusterRoleMapping{Edit: "hardened-edit"},
+			expected: map[string]string{"administrators": "admin", "editors": "hardened-edit", "viewers": "view"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			organization := newOrganization("acme")
+			organization.Spec.ClusterRoles = test.override
+			roleBindings := roleBindingsAssociatedWithSpace(newSpace("acme", "web"), organization, "acme-web-space", clusterRoles)
+			if roleRefs := roleRefsOf(roleBindings); !reflect.DeepEqual(roleRefs, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, roleRefs)
+			}
+		})
+	}
+}
+
+// roleBindingsInNamespace returns the RoleBindings found inside of namespace
+func roleBindingsInNamespace(t *testing.T, c *fakeCluster, namespace string) []*rbac.RoleBinding {
+	t.Helper()
+	list := &rbac.RoleBindingList{}
+	if err := c.List(context.Background(), list, client.InNamespace(namespace)); err != nil {
+		t.Fatal(err)
+	}
+	roleBindings := []*rbac.RoleBinding{}
+	for i := range list.Items {
+		roleBindings = append(roleBindings, &list.Items[i])
+	}
+	return roleBindings
+}
+
+func TestSpaceRoleBindingsFollowClusterRoles(t *testing.T) {
+	organization := newOrganization("acme")
+	organization.Spec.Editors = []string{"alice"}
+	c := newOrganizationCluster(t, organization, newSpace("acme", "web"))
+	c.reconcileSpace(t, "acme", "web")
+
+	c.get(t, "", "acme", organization)
+	organization.Spec.ClusterRoles = &k8sv1beta1.ClusterRoleMapping{Edit: "hardened-edit"}
+	if err := c.Update(context.Background(), organization); err != nil {
+		t.Fatal(err)
+	}
+	c.reconcileSpace(t, "acme", "web")
+
+	// The RoleBinding has been recreated, the temporary copy is gone
+	expected := map[string]string{"administrators": "admin", "editors": "hardened-edit", "viewers": "view"}
+	roleBindings := roleBindingsInNamespace(t, c, "acme-web-space")
+	if roleRefs := roleRefsOf(roleBindings); !reflect.DeepEqual(roleRefs, expected) {
+		t.Errorf("expected %v, got %v", expected, roleRefs)
+	}
+	editors := &rbac.RoleBinding{}
+	c.get(t, "acme-web-space", "editors", editors)
+	if len(editors.Subjects) != 1 || editors.Subjects[0].Name != "alice" {
+		t.Errorf("expected alice to be an editor, got %v", editors.Subjects)
+	}
+}
+
+func TestSpaceRemovesTemporaryRoleBinding(t *testing.T) {
+	space := newSpace("acme", "web")
+	// Left behind by a replacement of the RoleBinding editors that failed
+	temporary := &rbac.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "editors-0123abcd",
+			Namespace: "acme-web-space",
+			Labels:    labelsOfSpaceObjects(space, newOrganization("acme")),
+		},
+		RoleRef: rbac.RoleRef{APIGroup: rbac.GroupName, Kind: "ClusterRole", Name: "edit"},
+	}
+	c := newOrganizationCluster(t, newOrganization("acme"), space, temporary)
+	c.reconcileSpace(t, "acme", "web")
+
+	err := c.Get(context.Background(), client.ObjectKey{Name: temporary.Name, Namespace: "acme-web-space"}, temporary)
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the temporary RoleBinding to be deleted, got %v", err)
+	}
+}
+
+func TestCustomRoleBindingsOfSpace(t *testing.T) {
+	organization := newOrganization("acme")
+	space := newSpace("acme", "web")
+	space.Spec.RoleBindings = []k8sv1beta1.SpaceRoleBinding{
+		{Name: "deployer", ClusterRole: "deployer", Users: []string{"ci", "ci"}, Groups: []string{"release"}},
+		// Reserved by the operator
+		{Name: "administrators", ClusterRole: "cluster-admin", Users: []string{"mallory"}},
+	}
+
+	roleBindings := customRoleBindingsOfSpace(space, organization, "acme-web-space", logf.NullLogger{})
+	if len(roleBindings) != 1 {
+		t.Fatalf("expected one RoleBinding, got %v", roleBindings)
+	}
+	expected := common.NewRoleBinding("deployer", "acme-web-space", []string{"ci"}, []string{"release"}, nil,
+		rbac.RoleRef{APIGroup: rbac.GroupName, Kind: "ClusterRole", Name: "deployer"})
+	expected.SetLabels(labelsOfSpaceObjects(space, organization))
+	if !reflect.DeepEqual(roleBindings[0], expected) {
+		t.Errorf("expected %v, got %v", expected, roleBindings[0])
+	}
+}
+
+func TestMergeRoleBindings(t *testing.T) {
+	roleBinding := func(name, clusterRole string) *rbac.RoleBinding {
+		return common.NewRoleBinding(name, "acme-web-space", nil, nil, nil,
+			rbac.RoleRef{APIGroup: rbac.GroupName, Kind: "ClusterRole", Name: clusterRole})
+	}
+
+	merged := mergeRoleBindings(
+		[]*rbac.RoleBinding{roleBinding("administrators", "admin"), roleBinding("viewers", "view")},
+		[]*rbac.RoleBinding{roleBinding("deployer", "deployer")},
+		[]*rbac.RoleBinding{roleBinding("viewers", "restricted-view")},
+	)
+	names := []string{}
+	for _, roleBinding := range merged {
+		names = append(names, roleBinding.Name)
+	}
+	if expected := []string{"administrators", "viewers", "deployer"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+	if merged[1].RoleRef.Name != "restricted-view" {
+		t.Errorf("expected the last RoleBinding to win, got %v", merged[1].RoleRef)
+	}
+}
+
+func TestSpaceCustomRoleBindings(t *testing.T) {
+	space := newSpace("acme", "web")
+	space.Spec.RoleBindings = []k8sv1beta1.SpaceRoleBinding{
+		{Name: "deployer", ClusterRole: "deployer", Users: []string{"ci"}},
+		{Name: "debugger", ClusterRole: "debugger", Groups: []string{"oncall"}},
+	}
+	// Created by someone else, the operator must leave it alone
+	manual := &rbac.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "manual", Namespace: "acme-web-space"},
+		RoleRef:    rbac.RoleRef{APIGroup: rbac.GroupName, Kind: "ClusterRole", Name: "view"},
+	}
+	c := newOrganizationCluster(t, newOrganization("acme"), space, manual)
+	c.reconcileSpace(t, "acme", "web")
+
+	expected := map[string]string{
+		"administrators": "admin",
+		"editors":        "edit",
+		"viewers":        "view",
+		"deployer":       "deployer",
+		"debugger":       "debugger",
+		"manual":         "view",
+	}
+	if roleRefs := roleRefsOf(roleBindingsInNamespace(t, c, "acme-web-space")); !reflect.DeepEqual(roleRefs, expected) {
+		t.Errorf("expected %v, got %v", expected, roleRefs)
+	}
+	c.events()
+
+	// The RoleBindings of the removed entries are garbage collected
+	updateSpace(t, c, func(space *k8sv1beta1.Space) {
+		space.Spec.RoleBindings = space.Spec.RoleBindings[:1]
+	})
+	c.reconcileSpace(t, "acme", "web")
+	delete(expected, "debugger")
+	if roleRefs := roleRefsOf(roleBindingsInNamespace(t, c, "acme-web-space")); !reflect.DeepEqual(roleRefs, expected) {
+		t.Errorf("expected %v, got %v", expected, roleRefs)
+	}
+	assertEvent(t, c.events(), "Normal Deleted Deleted RoleBinding acme-web-space/debugger")
+}
+
+func TestSpaceRoleBindingNotManaged(t *testing.T) {
+	space := newSpace("acme", "web")
+	space.Spec.RoleBindings = []k8sv1beta1.SpaceRoleBinding{
+		{Name: "deployer", ClusterRole: "deployer", Users: []string{"ci"}},
+	}
+	existing := &rbac.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "acme-web-space"},
+		RoleRef:    rbac.RoleRef{APIGroup: rbac.GroupName, Kind: "ClusterRole", Name: "view"},
+	}
+	c := newOrganizationCluster(t, newOrganization("acme"), space, existing)
+
+	_, err := c.spaceReconciler().Reconcile(ctrl.Request{
+		NamespacedName: client.ObjectKey{Name: "web", Namespace: "acme-spaces"},
+	})
+	if err == nil {
+		t.Fatal("expected the reconciliation to fail")
+	}
+	c.get(t, "acme-spaces", "web", space)
+	assertCondition(t, space.Status.Conditions, k8sv1beta1.SpaceConditionRBACReady,
+		corev1.ConditionFalse, "RoleBindingNotManaged")
+	c.get(t, "acme-web-space", "deployer", existing)
+	if existing.RoleRef.Name != "view" {
+		t.Errorf("expected the RoleBinding to be left untouched, got %v", existing.RoleRef)
+	}
+}
+
+func TestNamespaceAssociatedWithSpace(t *testing.T) {
+	organization := newOrganization("acme")
+	organization.Spec.DefaultNamespaceLabels = map[string]string{
+		"tier":        "organization",
+		"cost-center": "acme",
+	}
+	organization.Spec.DefaultNamespaceAnnotations = map[string]string{
+		"backup":      "weekly",
+		"cost-center": "acme",
+	}
+	space := newSpace("acme", "web")
+	space.Spec.NamespaceLabels = map[string]string{
+		"tier":     "space",
+		"team":     "web",
+		labelSpace: "impostor",
+	}
+	space.Spec.NamespaceAnnotations = map[string]string{
+		"backup": "daily",
+		"owner":  "web",
+	}
+	extraConfig := &k8sv1beta1.SpaceExtraConfigSpec{
+		NamespaceLabels:      map[string]string{"team": "platform"},
+		NamespaceAnnotations: map[string]string{"owner": "platform"},
+	}
+
+	namespace := namespaceAssociatedWithSpace(space, organization, extraConfig)
+	if namespace.Name != "acme-web-space" {
+		t.Errorf("expected Namespace acme-web-space, got %s", namespace.Name)
+	}
+	expectedLabels := map[string]string{
+		"tier":            "space",
+		"cost-center":     "acme",
+		"team":            "platform",
+		labelOrganization: "acme",
+		labelSpace:        "web",
+	}
+	if !reflect.DeepEqual(namespace.GetLabels(), expectedLabels) {
+		t.Errorf("expected labels %v, got %v", expectedLabels, namespace.GetLabels())
+	}
+	expectedAnnotations := map[string]string{
+		"backup":      "daily",
+		"cost-center": "acme",
+		"owner":       "platform",
+	}
+	if !reflect.DeepEqual(namespace.GetAnnotations(), expectedAnnotations) {
+		t.Errorf("expected annotations %v, got %v", expectedAnnotations, namespace.GetAnnotations())
+	}
+
+	// Neither the Organization nor the Space are altered
+	if organization.Spec.DefaultNamespaceLabels["team"] != "" || space.Spec.NamespaceAnnotations["cost-center"] != "" {
+		t.Error("expected the maps of the Organization and of the Space to be left untouched")
+	}
+}
+
+func TestSpaceNamespaceAnnotations(t *testing.T) {
+	organization := newOrganization("acme")
+	organization.Spec.DefaultNamespaceAnnotations = map[string]string{"cost-center": "acme"}
+	space := newSpace("acme", "web")
+	space.Spec.NamespaceAnnotations = map[string]string{"backup": "daily"}
+	c := newOrganizationCluster(t, organization, space)
+	c.reconcileSpace(t, "acme", "web")
+
+	namespace := &corev1.Namespace{}
+	c.get(t, "", "acme-web-space", namespace)
+	annotations := namespace.GetAnnotations()
+	if annotations["cost-center"] != "acme" || annotations["backup"] != "daily" {
+		t.Fatalf("expected the annotations of the Organization and of the Space, got %v", annotations)
+	}
+
+	// Annotations added by someone else are preserved, the ones no longer
+	// wanted by the Space are removed
+	annotations["scheduler.alpha.kubernetes.io/node-selector"] = "zone=eu"
+	namespace.SetAnnotations(annotations)
+	if err := c.Update(context.Background(), namespace); err != nil {
+		t.Fatal(err)
+	}
+	updateSpace(t, c, func(space *k8sv1beta1.Space) {
+		space.Spec.NamespaceAnnotations = nil
+	})
+	c.reconcileSpace(t, "acme", "web")
+
+	namespace = &corev1.Namespace{}
+	c.get(t, "", "acme-web-space", namespace)
+	annotations = namespace.GetAnnotations()
+	if _, found := annotations["backup"]; found {
+		t.Errorf("expected the backup annotation to be removed, got %v", annotations)
+	}
+	if annotations["cost-center"] != "acme" || annotations["scheduler.alpha.kubernetes.io/node-selector"] != "zone=eu" {
+		t.Errorf("expected the other annotations to be preserved, got %v", annotations)
+	}
+}
+
+// deleteSpace requests the deletion of the Space acme-spaces/web, using the
+// given deletion policy
+func deleteSpace(t *testing.T, c *fakeCluster, policy k8sv1beta1.SpaceDeletionPolicy) {
+	t.Helper()
+	updateSpace(t, c, func(space *k8sv1beta1.Space) {
+		space.Spec.DeletionPolicy = policy
+		now := metav1.Now()
+		space.DeletionTimestamp = &now
+	})
+}
+
+// assertSpaceFinalizer checks whether the Space acme-spaces/web still has
+// its finalizer
+func assertSpaceFinalizer(t *testing.T, c *fakeCluster, expected bool) {
+	t.Helper()
+	space := &k8sv1beta1.Space{}
+	c.get(t, "acme-spaces", "web", space)
+	found := false
+	for _, finalizer := range space.GetFinalizers() {
+		found = found || finalizer == common.SpaceFinalizer
+	}
+	if found != expected {
+		t.Errorf("expected the finalizer to be present: %v, got finalizers %v",
+			expected, space.GetFinalizers())
+	}
+}
+
+func TestSpaceFinalizerWithoutOrganization(t *testing.T) {
+	organization := newOrganization("acme")
+	c := newOrganizationCluster(t, organization, newSpace("acme", "web"))
+	c.reconcileSpace(t, "acme", "web")
+	c.events()
+
+	// The Organization is gone, the Namespace holding the Spaces is still
+	// being garbage collected
+	if err := c.Delete(context.Background(), organization); err != nil {
+		t.Fatal(err)
+	}
+	deleteSpace(t, c, "")
+	c.reconcileSpace(t, "acme", "web")
+
+	expected := []string{"Normal Deleted Deleted Namespace acme-web-space"}
+	if events := c.events(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+	err := c.Get(context.Background(), client.ObjectKey{Name: "acme-web-space"}, &corev1.Namespace{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the Namespace of the Space to be deleted, got %v", err)
+	}
+
+	c.reconcileSpace(t, "acme", "web")
+	assertSpaceFinalizer(t, c, false)
+}
+
+// newSpaceWithWorkload returns a cluster where the Space acme-spaces/web has
+// been reconciled and its Namespace holds a ConfigMap not managed by the
+// operator
+func newSpaceWithWorkload(t *testing.T) *fakeCluster {
+	t.Helper()
+	space := newSpace("acme", "web")
+	space.Spec.Quota = &k8sv1beta1.SpaceQuota{Hard: corev1.ResourceList{
+		corev1.ResourcePods: resource.MustParse("10"),
+	}}
+	c := newOrganizationCluster(t, newOrganization("acme"), space)
+	c.reconcileSpace(t, "acme", "web")
+	c.events()
+
+	workload := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "acme-web-space"}}
+	if err := c.Create(context.Background(), workload); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestSpaceDeletionPolicyRetain(t *testing.T) {
+	c := newSpaceWithWorkload(t)
+	deleteSpace(t, c, k8sv1beta1.SpaceDeletionPolicyRetain)
+
+	if result := c.reconcileSpace(t, "acme", "web"); result.RequeueAfter != 0 {
+		t.Errorf("expected no requeue, got %v", result)
+	}
+	expected := []string{
+		"Normal NamespaceRetained Retained Namespace acme-web-space, removed the objects and the labels of the operator",
+	}
+	if events := c.events(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+	assertSpaceFinalizer(t, c, false)
+
+	namespace := &corev1.Namespace{}
+	c.get(t, "", "acme-web-space", namespace)
+	for _, label := range []string{labelOrganization, labelSpace} {
+		if _, found := namespace.GetLabels()[label]; found {
+			t.Errorf("expected label %s to be removed, got %v", label, namespace.GetLabels())
+		}
+	}
+	if roleBindings := roleBindingsInNamespace(t, c, "acme-web-space"); len(roleBindings) != 0 {
+		t.Errorf("expected the RoleBindings to be removed, got %v", roleRefsOf(roleBindings))
+	}
+	err := c.Get(context.Background(), client.ObjectKey{Name: resourceQuotaName, Namespace: "acme-web-space"}, &corev1.ResourceQuota{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the ResourceQuota to be removed, got %v", err)
+	}
+	c.get(t, "acme-web-space", "settings", &corev1.ConfigMap{})
+}
+
+func TestSpaceDeletionPolicyOrphan(t *testing.T) {
+	c := newSpaceWithWorkload(t)
+	before := &corev1.Namespace{}
+	c.get(t, "", "acme-web-space", before)
+	roleBindings := roleBindingsInNamespace(t, c, "acme-web-space")
+	deleteSpace(t, c, k8sv1beta1.SpaceDeletionPolicyOrphan)
+
+	if result := c.reconcileSpace(t, "acme", "web"); result.RequeueAfter != 0 {
+		t.Errorf("expected no requeue, got %v", result)
+	}
+	expected := []string{"Normal NamespaceOrphaned Left Namespace acme-web-space untouched"}
+	if events := c.events(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+	assertSpaceFinalizer(t, c, false)
+
+	after := &corev1.Namespace{}
+	c.get(t, "", "acme-web-space", after)
+	if !reflect.DeepEqual(after.GetLabels(), before.GetLabels()) {
+		t.Errorf("expected labels %v, got %v", before.GetLabels(), after.GetLabels())
+	}
+	if found := roleBindingsInNamespace(t, c, "acme-web-space"); len(found) != len(roleBindings) {
+		t.Errorf("expected %d RoleBindings, got %d", len(roleBindings), len(found))
+	}
+	c.get(t, "acme-web-space", resourceQuotaName, &corev1.ResourceQuota{})
+	c.get(t, "acme-web-space", "settings", &corev1.ConfigMap{})
+}
+
+func TestNamespaceTerminationBackoff(t *testing.T) {
+	tests := []struct {
+		deletedSince time.Duration
+		expected     time.Duration
+	}{
+		{0, minNamespaceTerminationBackoff},
+		{30 * time.Second, 30 * time.Second},
+		{time.Hour, maxNamespaceTerminationBackoff},
+	}
+
+	for _, test := range tests {
+		t.Run(test.deletedSince.String(), func(t *testing.T) {
+			space := newSpace("acme", "web")
+			deletedAt := metav1.NewTime(time.Now().Add(-test.deletedSince))
+			space.DeletionTimestamp = &deletedAt
+			if delay := namespaceTerminationBackoff(space); delay != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, delay)
+			}
+		})
+	}
+}
+
+func TestSpaceWaitsForNamespaceTermination(t *testing.T) {
+	c := newOrganizationCluster(t, newOrganization("acme"), newSpace("acme", "web"))
+	c.reconcileSpace(t, "acme", "web")
+	c.events()
+
+	// The Namespace is stuck in Terminating
+	namespace := &corev1.Namespace{}
+	c.get(t, "", "acme-web-space", namespace)
+	now := metav1.Now()
+	namespace.DeletionTimestamp = &now
+	namespace.Finalizers = []string{"example.com/backup"}
+	namespace.Spec.Finalizers = []corev1.FinalizerName{corev1.FinalizerKubernetes}
+	namespace.Status.Conditions = []corev1.NamespaceCondition{{
+		Type:    corev1.NamespaceContentRemaining,
+		Status:  corev1.ConditionTrue,
+		Message: "Some resources are remaining: pods. has 2 resource instances",
+	}}
+	if err := c.Update(context.Background(), namespace); err != nil {
+		t.Fatal(err)
+	}
+	deleteSpace(t, c, k8sv1beta1.SpaceDeletionPolicyDelete)
+
+	result := c.reconcileSpace(t, "acme", "web")
+	if result.RequeueAfter != minNamespaceTerminationBackoff {
+		t.Errorf("expected a requeue after %v, got %v", minNamespaceTerminationBackoff, result)
+	}
+	if events := c.events(); len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+	assertSpaceFinalizer(t, c, true)
+
+	space := &k8sv1beta1.Space{}
+	c.get(t, "acme-spaces", "web", space)
+	expected := []string{"example.com/backup", "kubernetes"}
+	if !reflect.DeepEqual(space.Status.NamespaceFinalizers, expected) {
+		t.Errorf("expected namespace finalizers %v, got %v", expected, space.Status.NamespaceFinalizers)
+	}
+	assertCondition(t, space.Status.Conditions, k8sv1beta1.SpaceConditionNamespaceReady,
+		corev1.ConditionFalse, "NamespaceTerminating")
+	ready := k8sv1beta1.FindCondition(space.Status.Conditions, k8sv1beta1.SpaceConditionNamespaceReady)
+	if ready != nil && ready.Message != "acme-web-space: Some resources are remaining: pods. has 2 resource instances" {
+		t.Errorf("unexpected message %q", ready.Message)
+	}
+
+	// The finalizer is released once the Namespace is gone
+	if err := c.Delete(context.Background(), namespace); err != nil {
+		t.Fatal(err)
+	}
+	if result = c.reconcileSpace(t, "acme", "web"); result.RequeueAfter != 0 {
+		t.Errorf("expected no requeue, got %v", result)
+	}
+	assertSpaceFinalizer(t, c, false)
+	space = &k8sv1beta1.Space{}
+	c.get(t, "acme-spaces", "web", space)
+	if len(space.Status.NamespaceFinalizers) != 0 {
+		t.Errorf("expected no namespace finalizers, got %v", space.Status.NamespaceFinalizers)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	rbac "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	k8sv1beta1 "github.com/flavio/organization-operator/api/v1beta1"
+)
+
+// extraConfigOfSpace returns the configuration obtained by merging all the
+// SpaceExtraConfig objects applying to the given Space
+func (r *SpaceReconciler) extraConfigOfSpace(space *k8sv1beta1.Space, ctx context.Context) (*k8sv1beta1.SpaceExtraConfigSpec, error) {
+	extraConfigs := &k8sv1beta1.SpaceExtraConfigList{}
+	if err := r.List(ctx, extraConfigs, client.InNamespace(space.Namespace)); err != nil {
+		return nil, err
+	}
+
+	return k8sv1beta1.MergeSpaceExtraConfigs(extraConfigs.Items, space.Name), nil
+}
+
+// extraRoleBindingsOfSpace returns the RoleBinding objects defined by the
+// SpaceExtraConfig objects applying to the Space
+func extraRoleBindingsOfSpace(
+	space *k8sv1beta1.Space,
+	organization *k8sv1beta1.Organization,
+	namespace string,
+	extraConfig *k8sv1beta1.SpaceExtraConfigSpec,
+	reqLogger logr.Logger) []*rbac.RoleBinding {
+	roleBindings := []*rbac.RoleBinding{}
+	for _, extra := range extraConfig.RoleBindings {
+		reqLogger.V(1).Info("Adding RoleBinding defined by SpaceExtraConfig",
+			"Namespace", namespace,
+			"RoleBinding", extra.Name)
+		roleBindings = append(roleBindings, &rbac.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      extra.Name,
+				Namespace: namespace,
+				Labels:    labelsOfSpaceObjects(space, organization),
+			},
+			Subjects: extra.Subjects,
+			RoleRef:  extra.RoleRef,
+		})
+	}
+	return roleBindings
+}
+
+// spacesOfExtraConfig returns a reconcile request for each one of the Space
+// objects the given SpaceExtraConfig could apply to
+func (r *SpaceReconciler) spacesOfExtraConfig(extraConfig *k8sv1beta1.SpaceExtraConfig) []ctrl.Request {
+	requests := []ctrl.Request{}
+
+	if len(extraConfig.Spec.Spaces) > 0 {
+		for _, name := range extraConfig.Spec.Spaces {
+			requests = append(requests, ctrl.Request{
+				NamespacedName: client.ObjectKey{
+					Name:      name,
+					Namespace: extraConfig.Namespace,
+				},
+			})
+		}
+		return requests
+	}
+
+	spaces := &k8sv1beta1.SpaceList{}
+	if err := r.List(context.Background(), spaces, client.InNamespace(extraConfig.Namespace)); err != nil {
+		r.Log.Error(err, "Cannot list Spaces affected by SpaceExtraConfig",
+			"SpaceExtraConfig.Name", extraConfig.Name,
+			"Namespace", extraConfig.Namespace)
+		return requests
+	}
+	for _, space := range spaces.Items {
+		requests = append(requests, ctrl.Request{
+			NamespacedName: client.ObjectKey{
+				Name:      space.Name,
+				Namespace: space.Namespace,
+			},
+		})
+	}
+	return requests
+}
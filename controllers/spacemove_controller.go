@@ -0,0 +1,413 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	rbac "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	k8sv1beta1 "github.com/flavio/organization-operator/api/v1beta1"
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// annotationMovedFrom is set on the Space created by a SpaceMove, it holds
+// the namespace and the name of the Space that has been moved
+const annotationMovedFrom = "organization-operator.k8s.suse.com/moved-from"
+
+// SpaceMoveReconciler reconciles a SpaceMove object
+type SpaceMoveReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=k8s.suse.com,resources=spacemoves,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=k8s.suse.com,resources=spacemoves/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile moves the Space referenced by the SpaceMove to the target
+// Organization. The move happens in three steps, each one of them can be
+// safely repeated:
+//  1. the Space is created inside of the target Organization, adopting the
+//     Namespace of the original Space
+//  2. the original Space is deleted, leaving its Namespace untouched
+//  3. the Namespace and the objects created by the operator inside of it
+//     are labelled with the target Organization
+//
+// The Space controller then rebuilds the RoleBindings of the Namespace.
+func (r *SpaceMoveReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	reqLogger := r.Log.WithValues("spacemove", req.NamespacedName)
+
+	reqLogger.Info("Reconciling SpaceMove")
+
+	instance := &k8sv1beta1.SpaceMove{}
+	err := r.Get(ctx, req.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if instance.Status.Phase == k8sv1beta1.SpaceMovePhaseCompleted ||
+		instance.Status.Phase == k8sv1beta1.SpaceMovePhaseFailed {
+		return ctrl.Result{}, nil
+	}
+
+	sourceOrganization, found, err := common.OrganizationOfSpacesNamespace(r, instance.Namespace, ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !found {
+		return ctrl.Result{}, r.fail(instance,
+			"SpaceMove must be created inside of the Namespace of an Organization", reqLogger, ctx)
+	}
+	targetOrganization := instance.Spec.TargetOrganization
+	if targetOrganization == sourceOrganization {
+		return ctrl.Result{}, r.fail(instance,
+			fmt.Sprintf("Space %s already belongs to Organization %s", instance.Spec.Space, targetOrganization),
+			reqLogger, ctx)
+	}
+
+	// The Space is found inside of the target Organization once the first
+	// step is done
+	movedFrom := instance.Namespace + "/" + instance.Spec.Space
+	target := &k8sv1beta1.Space{}
+	err = r.Get(ctx, client.ObjectKey{
+		Name:      instance.Spec.Space,
+		Namespace: common.ComputeSpacesNamespaceFromOrganizationName(targetOrganization),
+	}, target)
+	targetFound := err == nil
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	if targetFound && target.GetAnnotations()[annotationMovedFrom] != movedFrom {
+		return ctrl.Result{}, r.fail(instance,
+			fmt.Sprintf("Organization %s already has a Space named %s", targetOrganization, instance.Spec.Space),
+			reqLogger, ctx)
+	}
+
+	space := &k8sv1beta1.Space{}
+	err = r.Get(ctx, client.ObjectKey{Name: instance.Spec.Space, Namespace: instance.Namespace}, space)
+	spaceFound := err == nil
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	if !targetFound {
+		if !spaceFound {
+			return ctrl.Result{}, r.fail(instance,
+				fmt.Sprintf("Space %s not found", instance.Spec.Space), reqLogger, ctx)
+		}
+		if space.GetDeletionTimestamp() != nil {
+			return ctrl.Result{}, r.fail(instance,
+				fmt.Sprintf("Space %s is being deleted", instance.Spec.Space), reqLogger, ctx)
+		}
+		if err = r.checkTargetOrganization(targetOrganization, ctx); err != nil {
+			return ctrl.Result{}, r.fail(instance, err.Error(), reqLogger, ctx)
+		}
+
+		target = spaceMovedTo(space, sourceOrganization, targetOrganization, movedFrom)
+		// The SpaceMove has been authorized by its validating webhook, the
+		// target Organization is allowed to adopt the Namespace
+		if err = r.setAdoptableBy(target.Spec.AdoptNamespace, targetOrganization, ctx); err != nil {
+			return ctrl.Result{}, err
+		}
+		reqLogger.Info("Creating Space inside of the target Organization",
+			"Organization", targetOrganization,
+			"Namespace", target.Spec.AdoptNamespace)
+		if err = r.Create(ctx, target); err != nil {
+			if errors.IsInvalid(err) || errors.IsForbidden(err) {
+				// Rejected by the validating webhook
+				if err := r.setAdoptableBy(target.Spec.AdoptNamespace, "", ctx); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{}, r.fail(instance, err.Error(), reqLogger, ctx)
+			}
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonCreated,
+			"Created Space %s/%s", target.Namespace, target.Name)
+	}
+	instance.Status.Namespace = target.Spec.AdoptNamespace
+
+	if spaceFound {
+		if space.GetDeletionTimestamp() == nil {
+			if err = r.deleteMovedSpace(space, reqLogger, ctx); err != nil {
+				return ctrl.Result{}, err
+			}
+			r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonDeleted,
+				"Deleted Space %s/%s, its Namespace has been left untouched", space.Namespace, space.Name)
+		}
+		// Wait for the Space to be finalized, the SpaceMove is reconciled
+		// again once the Space is gone
+		return ctrl.Result{}, r.setPhase(instance, k8sv1beta1.SpaceMovePhaseMoving,
+			fmt.Sprintf("Waiting for Space %s to be removed from Organization %s", space.Name, sourceOrganization),
+			reqLogger, ctx)
+	}
+
+	if err = r.relabelNamespace(instance, sourceOrganization, reqLogger, ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonMoved,
+		"Moved Space %s to Organization %s", instance.Spec.Space, targetOrganization)
+	return ctrl.Result{}, r.setPhase(instance, k8sv1beta1.SpaceMovePhaseCompleted,
+		fmt.Sprintf("Space %s moved to Organization %s", instance.Spec.Space, targetOrganization),
+		reqLogger, ctx)
+}
+
+// checkTargetOrganization ensures the Space can be moved to the Organization
+func (r *SpaceMoveReconciler) checkTargetOrganization(name string, ctx context.Context) error {
+	organization := &k8sv1beta1.Organization{}
+	if err := r.Get(ctx, client.ObjectKey{Name: name}, organization); err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("Organization %s does not exist", name)
+		}
+		return err
+	}
+	if organization.GetDeletionTimestamp() != nil {
+		return fmt.Errorf("Organization %s is being deleted", name)
+	}
+	return nil
+}
+
+// spaceMovedTo returns the Space to create inside of the target Organization.
+// The new Space adopts the Namespace of the original one.
+func spaceMovedTo(space *k8sv1beta1.Space, sourceOrganization, targetOrganization, movedFrom string) *k8sv1beta1.Space {
+	annotations := mergeMaps(space.GetAnnotations(), map[string]string{
+		annotationMovedFrom: movedFrom,
+	})
+	target := &k8sv1beta1.Space{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        space.Name,
+			Namespace:   common.ComputeSpacesNamespaceFromOrganizationName(targetOrganization),
+			Labels:      space.GetLabels(),
+			Annotations: annotations,
+		},
+		Spec: *space.Spec.DeepCopy(),
+	}
+	target.Spec.AdoptNamespace = space.NamespaceName(sourceOrganization)
+	if target.Spec.DeletionPolicy == "" {
+		// Keep the behaviour of the original Space, an adopted Namespace
+		// would be retained otherwise
+		target.Spec.DeletionPolicy = k8sv1beta1.SpaceDeletionPolicyDelete
+	}
+	return target
+}
+
+// deleteMovedSpace deletes the original Space making sure its Namespace is
+// left untouched
+func (r *SpaceMoveReconciler) deleteMovedSpace(space *k8sv1beta1.Space, reqLogger logr.Logger, ctx context.Context) error {
+	if space.Spec.DeletionPolicy != k8sv1beta1.SpaceDeletionPolicyOrphan {
+		space.Spec.DeletionPolicy = k8sv1beta1.SpaceDeletionPolicyOrphan
+		if err := r.Update(ctx, space); err != nil {
+			return err
+		}
+	}
+
+	reqLogger.Info("Deleting moved Space",
+		"Namespace", space.Namespace,
+		"Name", space.Name)
+	if err := r.Delete(ctx, space); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// relabelNamespace labels the Namespace of the moved Space, and the objects
+// created by the operator inside of it, with the target Organization
+func (r *SpaceMoveReconciler) relabelNamespace(
+	instance *k8sv1beta1.SpaceMove,
+	sourceOrganization string,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	namespace := &corev1.Namespace{}
+	err := r.Get(ctx, client.ObjectKey{Name: instance.Status.Namespace}, namespace)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	sourceLabels := map[string]string{
+		labelOrganization: sourceOrganization,
+		labelSpace:        instance.Spec.Space,
+	}
+	lists := []runtime.Object{
+		&rbac.RoleBindingList{},
+		&corev1.ResourceQuotaList{},
+		&corev1.LimitRangeList{},
+		&networking.NetworkPolicyList{},
+	}
+	for _, list := range lists {
+		err = r.List(ctx, list, client.InNamespace(namespace.Name), client.MatchingLabels(sourceLabels))
+		if err != nil {
+			return err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err = r.relabel(item, instance.Spec.TargetOrganization, reqLogger, ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if namespace.GetLabels()[labelOrganization] != sourceOrganization {
+		return nil
+	}
+	// The Namespace has been adopted, it must not be adoptable anymore
+	annotations := namespace.GetAnnotations()
+	delete(annotations, common.AdoptableByAnnotation)
+	namespace.SetAnnotations(annotations)
+	return r.relabel(namespace, instance.Spec.TargetOrganization, reqLogger, ctx)
+}
+
+// setAdoptableBy allows the Spaces of the given Organization to adopt the
+// Namespace, an empty organizationName removes the permission
+func (r *SpaceMoveReconciler) setAdoptableBy(namespaceName, organizationName string, ctx context.Context) error {
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: namespaceName}, namespace); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	annotations := namespace.GetAnnotations()
+	if annotations[common.AdoptableByAnnotation] == organizationName {
+		return nil
+	}
+	if organizationName == "" {
+		delete(annotations, common.AdoptableByAnnotation)
+	} else {
+		annotations = mergeMaps(annotations, map[string]string{
+			common.AdoptableByAnnotation: organizationName,
+		})
+	}
+	namespace.SetAnnotations(annotations)
+	return r.Update(ctx, namespace)
+}
+
+// relabel changes the Organization label of the given object
+func (r *SpaceMoveReconciler) relabel(object runtime.Object, organizationName string, reqLogger logr.Logger, ctx context.Context) error {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return err
+	}
+
+	reqLogger.Info("Moving object to the target Organization",
+		"Kind", fmt.Sprintf("%T", object),
+		"Namespace", accessor.GetNamespace(),
+		"Name", accessor.GetName())
+	labels := map[string]string{
+		labelOrganization: organizationName,
+	}
+	if namespace, ok := object.(*corev1.Namespace); ok {
+		// The applied state of the Namespace covers its labels, it must be
+		// updated together with them
+		common.SetNamespaceLabels(namespace, labels)
+	} else {
+		accessor.SetLabels(mergeMaps(accessor.GetLabels(), labels))
+	}
+	if err = r.Update(ctx, object); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// fail marks the SpaceMove as failed
+func (r *SpaceMoveReconciler) fail(instance *k8sv1beta1.SpaceMove, message string, reqLogger logr.Logger, ctx context.Context) error {
+	reqLogger.Info("Cannot move Space", "reason", message)
+	r.Recorder.Event(instance, corev1.EventTypeWarning, eventReasonMoveFailed, message)
+	return r.setPhase(instance, k8sv1beta1.SpaceMovePhaseFailed, message, reqLogger, ctx)
+}
+
+// setPhase updates the status of the SpaceMove
+func (r *SpaceMoveReconciler) setPhase(
+	instance *k8sv1beta1.SpaceMove,
+	phase k8sv1beta1.SpaceMovePhase,
+	message string,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	instance.Status.Phase = phase
+	instance.Status.Message = message
+	if err := r.Status().Update(ctx, instance); err != nil {
+		reqLogger.Error(err, "Cannot update status of SpaceMove")
+		return err
+	}
+	return nil
+}
+
+// spaceMovesOfSpace returns a reconcile request for each one of the SpaceMove
+// objects referencing the given Space
+func (r *SpaceMoveReconciler) spaceMovesOfSpace(a handler.MapObject) []ctrl.Request {
+	requests := []ctrl.Request{}
+
+	spaceMoves := &k8sv1beta1.SpaceMoveList{}
+	if err := r.List(context.Background(), spaceMoves, client.InNamespace(a.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "Cannot list SpaceMoves affected by Space",
+			"Space.Name", a.Meta.GetName(),
+			"Namespace", a.Meta.GetNamespace())
+		return requests
+	}
+	for _, spaceMove := range spaceMoves.Items {
+		if spaceMove.Spec.Space != a.Meta.GetName() {
+			continue
+		}
+		requests = append(requests, ctrl.Request{
+			NamespacedName: client.ObjectKey{
+				Name:      spaceMove.Name,
+				Namespace: spaceMove.Namespace,
+			},
+		})
+	}
+	return requests
+}
+
+func (r *SpaceMoveReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&k8sv1beta1.SpaceMove{}).
+		// The move goes on once the original Space is gone
+		Watches(
+			&source.Kind{Type: &k8sv1beta1.Space{}},
+			&handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.spaceMovesOfSpace),
+			},
+		).
+		Complete(r)
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	k8sv1beta1 "github.com/flavio/organization-operator/api/v1beta1"
+	"github.com/flavio/organization-operator/pkg/common"
+)
+
+// finalizingClient honours the finalizers like the API server does: the
+// objects having finalizers are only marked as being deleted, they are
+// removed once their finalizers are gone
+type finalizingClient struct {
+	client.Client
+}
+
+func (c *finalizingClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	if len(accessor.GetFinalizers()) == 0 {
+		return c.Client.Delete(ctx, obj, opts...)
+	}
+	if accessor.GetDeletionTimestamp() == nil {
+		now := metav1.Now()
+		accessor.SetDeletionTimestamp(&now)
+	}
+	return c.Client.Update(ctx, obj)
+}
+
+func (c *finalizingClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	if accessor.GetDeletionTimestamp() != nil && len(accessor.GetFinalizers()) == 0 {
+		return c.Client.Delete(ctx, obj)
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// newSpaceMoveCluster returns a cluster where the Space web of the
+// Organization acme has been reconciled, together with the Organization other
+func newSpaceMoveCluster(t *testing.T, objects ...runtime.Object) *fakeCluster {
+	t.Helper()
+	objects = append([]runtime.Object{
+		newOrganization("acme"),
+		newOrganization("other"),
+		newSpace("acme", "web"),
+	}, objects...)
+	c := newFakeCluster()
+	c.Client = &finalizingClient{Client: fake.NewFakeClientWithScheme(scheme.Scheme, objects...)}
+	c.reconcileOrganization(t, "acme")
+	c.reconcileOrganization(t, "other")
+	c.reconcileSpace(t, "acme", "web")
+	c.events()
+	return c
+}
+
+func newSpaceMove(space, targetOrganization string) *k8sv1beta1.SpaceMove {
+	return &k8sv1beta1.SpaceMove{
+		ObjectMeta: metav1.ObjectMeta{Name: "move-" + space, Namespace: "acme-spaces"},
+		Spec: k8sv1beta1.SpaceMoveSpec{
+			Space:              space,
+			TargetOrganization: targetOrganization,
+		},
+	}
+}
+
+// assertSpaceMovePhase checks the phase of the SpaceMove acme-spaces/move-web
+func assertSpaceMovePhase(t *testing.T, c *fakeCluster, phase k8sv1beta1.SpaceMovePhase) *k8sv1beta1.SpaceMove {
+	t.Helper()
+	spaceMove := &k8sv1beta1.SpaceMove{}
+	c.get(t, "acme-spaces", "move-web", spaceMove)
+	if spaceMove.Status.Phase != phase {
+		t.Errorf("expected phase %s, got %s: %s", phase, spaceMove.Status.Phase, spaceMove.Status.Message)
+	}
+	return spaceMove
+}
+
+func TestSpaceMove(t *testing.T) {
+	workload := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "acme-web-space"}}
+	c := newSpaceMoveCluster(t, newSpaceMove("web", "other"), workload)
+
+	// The Space is created inside of the target Organization, the original
+	// one is deleted leaving its Namespace untouched
+	c.reconcileSpaceMove(t, "acme", "move-web")
+	expected := []string{
+		"Normal Created Created Space other-spaces/web",
+		"Normal Deleted Deleted Space acme-spaces/web, its Namespace has been left untouched",
+	}
+	if events := c.events(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+	spaceMove := assertSpaceMovePhase(t, c, k8sv1beta1.SpaceMovePhaseMoving)
+	if spaceMove.Status.Namespace != "acme-web-space" {
+		t.Errorf("expected Namespace acme-web-space, got %q", spaceMove.Status.Namespace)
+	}
+
+	target := &k8sv1beta1.Space{}
+	c.get(t, "other-spaces", "web", target)
+	if target.Spec.AdoptNamespace != "acme-web-space" {
+		t.Errorf("expected the Namespace acme-web-space to be adopted, got %q", target.Spec.AdoptNamespace)
+	}
+	if movedFrom := target.GetAnnotations()[annotationMovedFrom]; movedFrom != "acme-spaces/web" {
+		t.Errorf("expected the Space to be moved from acme-spaces/web, got %q", movedFrom)
+	}
+	source := &k8sv1beta1.Space{}
+	c.get(t, "acme-spaces", "web", source)
+	if source.GetDeletionTimestamp() == nil || source.Spec.DeletionPolicy != k8sv1beta1.SpaceDeletionPolicyOrphan {
+		t.Errorf("expected the Space to be deleted with the Orphan policy, got policy %q", source.Spec.DeletionPolicy)
+	}
+	namespace := &corev1.Namespace{}
+	c.get(t, "", "acme-web-space", namespace)
+	if adoptableBy := namespace.GetAnnotations()[common.AdoptableByAnnotation]; adoptableBy != "other" {
+		t.Errorf("expected the Namespace to be adoptable by other, got %q", adoptableBy)
+	}
+
+	// Nothing changes while the original Space is being finalized
+	c.reconcileSpaceMove(t, "acme", "move-web")
+	if events := c.events(); len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+	assertSpaceMovePhase(t, c, k8sv1beta1.SpaceMovePhaseMoving)
+
+	c.reconcileSpace(t, "acme", "web")
+	err := c.Get(context.Background(), client.ObjectKey{Name: "web", Namespace: "acme-spaces"}, &k8sv1beta1.Space{})
+	if !errors.IsNotFound(err) {
+		t.Fatalf("expected the original Space to be gone, got %v", err)
+	}
+	c.events()
+
+	// The Namespace and the objects of the operator are labelled with the
+	// target Organization
+	c.reconcileSpaceMove(t, "acme", "move-web")
+	expected = []string{"Normal Moved Moved Space web to Organization other"}
+	if events := c.events(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+	assertSpaceMovePhase(t, c, k8sv1beta1.SpaceMovePhaseCompleted)
+
+	namespace = &corev1.Namespace{}
+	c.get(t, "", "acme-web-space", namespace)
+	if organization := namespace.GetLabels()[labelOrganization]; organization != "other" {
+		t.Errorf("expected the Namespace to belong to other, got %q", organization)
+	}
+	if _, found := namespace.GetAnnotations()[common.AdoptableByAnnotation]; found {
+		t.Errorf("expected the Namespace to be no longer adoptable, got %v", namespace.GetAnnotations())
+	}
+	for _, roleBinding := range roleBindingsInNamespace(t, c, "acme-web-space") {
+		if organization := roleBinding.GetLabels()[labelOrganization]; organization != "other" {
+			t.Errorf("expected RoleBinding %s to belong to other, got %q", roleBinding.Name, organization)
+		}
+	}
+	c.get(t, "acme-web-space", "settings", &corev1.ConfigMap{})
+
+	// The moved Space takes over the Namespace
+	c.reconcileSpace(t, "other", "web")
+	target = &k8sv1beta1.Space{}
+	c.get(t, "other-spaces", "web", target)
+	if target.Status.Namespace != "acme-web-space" {
+		t.Errorf("expected the Space to manage acme-web-space, got %q", target.Status.Namespace)
+	}
+}
+
+func TestSpaceMoveFailures(t *testing.T) {
+	now := metav1.Now()
+	deletedOrganization := newOrganization("leaving")
+	deletedOrganization.Finalizers = []string{common.OrganizationFinalizer}
+	deletedOrganization.DeletionTimestamp = &now
+
+	tests := []struct {
+		name      string
+		spaceMove *k8sv1beta1.SpaceMove
+		objects   []runtime.Object
+		message   string
+	}{
+		{
+			"same Organization",
+			newSpaceMove("web", "acme"),
+			nil,
+			"Space web already belongs to Organization acme",
+		},
+		{
+			"missing Space",
+			newSpaceMove("api", "other"),
+			nil,
+			"Space api not found",
+		},
+		{
+			"Space name taken",
+			newSpaceMove("web", "other"),
+			[]runtime.Object{newSpace("other", "web")},
+			"Organization other already has a Space named web",
+		},
+		{
+			"missing Organization",
+			newSpaceMove("web", "ghost"),
+			nil,
+			"Organization ghost does not exist",
+		},
+		{
+			"Organization being deleted",
+			newSpaceMove("web", "leaving"),
+			[]runtime.Object{deletedOrganization},
+			"Organization leaving is being deleted",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newSpaceMoveCluster(t, append(test.objects, test.spaceMove)...)
+			c.reconcileSpaceMove(t, "acme", test.spaceMove.Name)
+
+			expected := []string{"Warning MoveFailed " + test.message}
+			if events := c.events(); !reflect.DeepEqual(events, expected) {
+				t.Errorf("expected %v, got %v", expected, events)
+			}
+			spaceMove := &k8sv1beta1.SpaceMove{}
+			c.get(t, "acme-spaces", test.spaceMove.Name, spaceMove)
+			if spaceMove.Status.Phase != k8sv1beta1.SpaceMovePhaseFailed || spaceMove.Status.Message != test.message {
+				t.Errorf("expected phase %s with message %q, got %+v",
+					k8sv1beta1.SpaceMovePhaseFailed, test.message, spaceMove.Status)
+			}
+
+			// Nothing has been changed
+			space := &k8sv1beta1.Space{}
+			c.get(t, "acme-spaces", "web", space)
+			if space.GetDeletionTimestamp() != nil {
+				t.Error("expected the Space to be left untouched")
+			}
+
+			// A failed move is not retried
+			c.reconcileSpaceMove(t, "acme", test.spaceMove.Name)
+			if events := c.events(); len(events) != 0 {
+				t.Errorf("expected no events, got %v", events)
+			}
+		})
+	}
+}
+
+func TestSpaceMovesOfSpace(t *testing.T) {
+	c := newFakeCluster(
+		newSpaceMove("web", "other"),
+		newSpaceMove("api", "other"),
+	)
+	space := newSpace("acme", "web")
+	requests := c.spaceMoveReconciler().spaceMovesOfSpace(handler.MapObject{Meta: space, Object: space})
+
+	expected := []ctrl.Request{{
+		NamespacedName: client.ObjectKey{Name: "move-web", Namespace: "acme-spaces"},
+	}}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected %v, got %v", expected, requests)
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	k8sv1alpha1 "github.com/flavio/organization-operator/api/v1alpha1"
+	k8sv1beta1 "github.com/flavio/organization-operator/api/v1beta1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -65,7 +66,7 @@ var _ = BeforeSuite(func(done Done) {
 	err = k8sv1alpha1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
-	err = k8sv1alpha1.AddToScheme(scheme.Scheme)
+	err = k8sv1beta1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
 	// +kubebuilder:scaffold:scheme
@@ -27,7 +27,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	k8sv1alpha1 "github.com/flavio/organization-operator/api/v1alpha1"
+	k8sv1beta1 "github.com/flavio/organization-operator/api/v1beta1"
 	"github.com/flavio/organization-operator/controllers"
+	"github.com/flavio/organization-operator/pkg/common"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -40,20 +42,40 @@ func init() {
 	_ = clientgoscheme.AddToScheme(scheme)
 
 	_ = k8sv1alpha1.AddToScheme(scheme)
+	_ = k8sv1beta1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var clusterRoles k8sv1beta1.ClusterRoleMapping
+	var spaceNamespaceTemplate, spacesNamespaceTemplate string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&clusterRoles.Admin, "admin-cluster-role", "admin",
+		"The ClusterRole granted to the admins of a Space.")
+	flag.StringVar(&clusterRoles.Edit, "edit-cluster-role", "edit",
+		"The ClusterRole granted to the editors of a Space.")
+	flag.StringVar(&clusterRoles.View, "view-cluster-role", "view",
+		"The ClusterRole granted to the viewers of a Space.")
+	flag.StringVar(&spaceNamespaceTemplate, "space-namespace-template", common.DefaultSpaceNamespaceTemplate,
+		"The template of the name of the Namespace of a Space. "+
+			"Names longer than 63 characters are truncated and suffixed with a hash.")
+	flag.StringVar(&spacesNamespaceTemplate, "spaces-namespace-template", common.DefaultSpacesNamespaceTemplate,
+		"The template of the name of the Namespace holding the Spaces of an Organization. "+
+			"Names longer than 63 characters are truncated and suffixed with a hash.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
+	if err := common.SetNamespaceNameTemplates(spaceNamespaceTemplate, spacesNamespaceTemplate); err != nil {
+		setupLog.Error(err, "invalid Namespace name template")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:             scheme,
 		MetricsBindAddress: metricsAddr,
@@ -67,26 +89,58 @@ func main() {
 	}
 
 	if err = (&controllers.OrganizationReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Organization"),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("Organization"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("organization-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Organization")
 		os.Exit(1)
 	}
 	if err = (&controllers.SpaceReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Space"),
-		Scheme: mgr.GetScheme(),
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("Space"),
+		Scheme:       mgr.GetScheme(),
+		Recorder:     mgr.GetEventRecorderFor("space-controller"),
+		ClusterRoles: clusterRoles,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Space")
 		os.Exit(1)
 	}
+	if err = (&controllers.SpaceMoveReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("SpaceMove"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("spacemove-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "SpaceMove")
+		os.Exit(1)
+	}
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
 		if err = (&k8sv1alpha1.Space{}).SetupWebhookWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Space")
 			os.Exit(1)
 		}
+		if err = (&k8sv1beta1.Space{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Space")
+			os.Exit(1)
+		}
+		if err = (&k8sv1alpha1.Organization{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Organization")
+			os.Exit(1)
+		}
+		if err = (&k8sv1beta1.Organization{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Organization")
+			os.Exit(1)
+		}
+		if err = (&k8sv1beta1.SpaceExtraConfig{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "SpaceExtraConfig")
+			os.Exit(1)
+		}
+		if err = (&k8sv1beta1.SpaceMove{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "SpaceMove")
+			os.Exit(1)
+		}
 	}
 	// +kubebuilder:scaffold:builder
 
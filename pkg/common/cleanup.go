@@ -0,0 +1,54 @@
+package common
+
+import (
+	"context"
+
+	logr "github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DeleteManagedObject deletes the object with the given name and namespace
+// when it exists and it carries all the given labels. Objects that have not
+// been created by the operator are left untouched. It returns true when the
+// object has been deleted.
+func DeleteManagedObject(
+	c client.Client,
+	obj runtime.Object,
+	namespace, name string,
+	managedLabels map[string]string,
+	reqLogger logr.Logger,
+	ctx context.Context) (bool, error) {
+	err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, obj)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	labels := accessor.GetLabels()
+	for key, value := range managedLabels {
+		if labels[key] != value {
+			reqLogger.Info("Not deleting object not managed by the operator",
+				"Namespace", namespace,
+				"Name", name)
+			return false, nil
+		}
+	}
+
+	reqLogger.Info("Deleting object no longer wanted",
+		"Namespace", namespace,
+		"Name", name)
+	if err = c.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
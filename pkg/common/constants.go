@@ -1,3 +1,29 @@
 package common
 
 const SpaceFinalizer = "organization-operator.k8s.suse.com"
+
+// OrganizationFinalizer is set on Organization objects, it allows the
+// operator to clean up the Organization before it is removed
+const OrganizationFinalizer = "organization-operator.k8s.suse.com/organization"
+
+// ReservedKeyPrefix is the prefix of the label and annotation keys used by
+// the operator. Users cannot set keys with this prefix.
+const ReservedKeyPrefix = "organization-operator.k8s.suse.com/"
+
+// organizationAPIGroup is the API group of the Organization objects
+const organizationAPIGroup = "k8s.suse.com"
+
+// Names of the RoleBindings created by the operator inside of the Namespace
+// of each Space
+var builtinRoleBindingNames = []string{"administrators", "editors", "viewers"}
+
+// IsBuiltinRoleBindingName returns true when the name is used by one of the
+// RoleBindings created by the operator inside of the Namespace of each Space
+func IsBuiltinRoleBindingName(name string) bool {
+	for _, builtin := range builtinRoleBindingNames {
+		if name == builtin {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,10 @@
 package common
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"sort"
+	"strings"
 
 	logr "github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -15,20 +16,68 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+// ManagedLabelsAnnotation holds the comma separated list of the label keys
+// set by the operator on a Namespace. Only these keys are changed or removed
+// by the operator, the other labels are left untouched.
+const ManagedLabelsAnnotation = "organization-operator.k8s.suse.com/managed-labels"
+
+// ManagedAnnotationsAnnotation is the equivalent of ManagedLabelsAnnotation
+// for the annotations of a Namespace
+const ManagedAnnotationsAnnotation = "organization-operator.k8s.suse.com/managed-annotations"
+
+// AdoptionAnnotation holds the original value of the labels and annotations
+// changed by the operator when it adopted an existing Namespace. It is used
+// to undo the adoption.
+const AdoptionAnnotation = "organization-operator.k8s.suse.com/adoption"
+
+// AdoptableByAnnotation must be set on an existing Namespace, by someone
+// allowed to change Namespaces, before a Space can adopt it. Its value is the
+// name of the Organization whose Spaces can adopt the Namespace.
+const AdoptableByAnnotation = "organization-operator.k8s.suse.com/adoptable-by"
+
+// namespaceAdoption is the content of AdoptionAnnotation
+type namespaceAdoption struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// namespaceState is the part of a Namespace managed by the operator
+type namespaceState struct {
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// ReconcileNamespace ensures the given Namespace exists and has the right set
+// of labels and annotations. Only the labels and annotations owned by the
+// operator are reconciled: the ones added by someone else are preserved, the
+// ones set by the operator but no longer desired are removed.
+// It returns the operation performed against the cluster,
+// OperationResultDriftCorrected is returned when the owned labels or
+// annotations have been changed by someone else.
 func ReconcileNamespace(
 	client client.Client,
 	namespace *corev1.Namespace,
 	owner metav1.Object,
 	scheme *runtime.Scheme,
 	reqLogger logr.Logger,
-	ctx context.Context) error {
+	ctx context.Context) (controllerutil.OperationResult, error) {
 	// Set Organization instance as the owner and controller
 	if owner != nil && scheme != nil {
 		if err := controllerutil.SetControllerReference(owner, namespace, scheme); err != nil {
-			return err
+			return controllerutil.OperationResultNone, err
 		}
 	}
 
+	desiredLabels := namespace.GetLabels()
+	desiredAnnotations := withoutBookkeeping(namespace.GetAnnotations())
+	bookkeeping := setAppliedState(map[string]string{}, namespaceState{
+		Labels:      desiredLabels,
+		Annotations: desiredAnnotations,
+	})
+	bookkeeping[ManagedLabelsAnnotation] = joinKeys(desiredLabels)
+	bookkeeping[ManagedAnnotationsAnnotation] = joinKeys(desiredAnnotations)
+	namespace.SetAnnotations(mergeAnnotations(desiredAnnotations, bookkeeping))
+
 	// Check if this Namespace already exists
 	found := &corev1.Namespace{}
 	err := client.Get(context.TODO(), types.NamespacedName{Name: namespace.Name}, found)
@@ -38,31 +87,242 @@ func ReconcileNamespace(
 				"Creating a new Namespace",
 				"Namespace", namespace.Name,
 				"Labels", namespace.Labels)
-			return client.Create(ctx, namespace)
+			return createResult(client.Create(ctx, namespace))
 		}
-		return err
+		return controllerutil.OperationResultNone, err
 	}
 
-	if !namespacesHaveSameLabels(found, namespace) {
-		found.SetLabels(namespace.GetLabels())
-		reqLogger.Info("Updating namespace to have the right set of labels",
-			"Namespace", found.Name)
-		return client.Update(ctx, found)
+	// Namespaces created by older releases of the operator don't track the
+	// labels and annotations they own
+	foundAnnotations := found.GetAnnotations()
+	ownedLabelsValue, tracked := foundAnnotations[ManagedLabelsAnnotation]
+	ownedLabels := splitKeys(ownedLabelsValue)
+	ownedAnnotationsValue := foundAnnotations[ManagedAnnotationsAnnotation]
+	ownedAnnotations := splitKeys(ownedAnnotationsValue)
+
+	labels, labelsChanged := reconcileOwnedKeys(found.GetLabels(), desiredLabels, ownedLabels)
+	annotations, annotationsChanged := reconcileOwnedKeys(foundAnnotations, desiredAnnotations, ownedAnnotations)
+	if !labelsChanged && !annotationsChanged &&
+		ownedLabelsValue == bookkeeping[ManagedLabelsAnnotation] &&
+		ownedAnnotationsValue == bookkeeping[ManagedAnnotationsAnnotation] {
+		return controllerutil.OperationResultNone, nil
 	}
 
-	return nil
+	drifted := tracked && hasDrifted(foundAnnotations, namespaceState{
+		Labels:      selectKeys(found.GetLabels(), ownedLabels),
+		Annotations: selectKeys(foundAnnotations, ownedAnnotations),
+	})
+	found.SetLabels(labels)
+	found.SetAnnotations(mergeAnnotations(annotations, bookkeeping))
+	reqLogger.Info("Updating namespace to have the right set of labels and annotations",
+		"Namespace", found.Name,
+		"Drifted", drifted)
+	return updateResult(drifted, client.Update(ctx, found))
 }
 
-func namespacesHaveSameLabels(a, b *corev1.Namespace) bool {
-	mA, err := json.Marshal(a.GetLabels())
+// AdoptNamespace records inside of the existing Namespace the original value
+// of the labels and annotations the desired Namespace is going to change. The
+// Namespace can then be reconciled with ReconcileNamespace, while
+// ReleaseNamespace restores the original values.
+func AdoptNamespace(
+	client client.Client,
+	existing *corev1.Namespace,
+	desired *corev1.Namespace,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	adoption := namespaceAdoption{
+		Labels:      overriddenValues(existing.GetLabels(), desired.GetLabels()),
+		Annotations: overriddenValues(existing.GetAnnotations(), withoutBookkeeping(desired.GetAnnotations())),
+	}
+	data, err := json.Marshal(adoption)
 	if err != nil {
-		return false
+		return err
 	}
 
-	mB, err := json.Marshal(b.GetLabels())
-	if err != nil {
-		return false
+	reqLogger.Info("Adopting existing Namespace",
+		"Namespace", existing.Name,
+		"OriginalLabels", adoption.Labels,
+		"OriginalAnnotations", adoption.Annotations)
+	existing.SetAnnotations(mergeAnnotations(existing.GetAnnotations(), map[string]string{
+		AdoptionAnnotation: string(data),
+	}))
+	return client.Update(ctx, existing)
+}
+
+// ReleaseNamespace removes from the Namespace the labels and the annotations
+// owned by the operator, plus the given labels. The labels and annotations
+// changed when the Namespace was adopted are restored to their original
+// value. The Namespace is no longer managed by the operator afterwards.
+func ReleaseNamespace(
+	client client.Client,
+	namespace *corev1.Namespace,
+	labels []string,
+	reqLogger logr.Logger,
+	ctx context.Context) error {
+	annotations := namespace.GetAnnotations()
+	ownedLabels := append(splitKeys(annotations[ManagedLabelsAnnotation]), labels...)
+	ownedAnnotations := append(splitKeys(annotations[ManagedAnnotationsAnnotation]),
+		AppliedStateAnnotation, ManagedLabelsAnnotation, ManagedAnnotationsAnnotation, AdoptionAnnotation)
+
+	adoption := namespaceAdoption{}
+	if data, adopted := annotations[AdoptionAnnotation]; adopted {
+		if err := json.Unmarshal([]byte(data), &adoption); err != nil {
+			reqLogger.Error(err, "Cannot restore the original labels and annotations of the Namespace",
+				"Namespace", namespace.Name)
+		}
+	}
+
+	newLabels, labelsChanged := reconcileOwnedKeys(namespace.GetLabels(), nil, ownedLabels)
+	newAnnotations, annotationsChanged := reconcileOwnedKeys(annotations, nil, ownedAnnotations)
+	newLabels, labelsRestored := reconcileOwnedKeys(newLabels, adoption.Labels, nil)
+	newAnnotations, annotationsRestored := reconcileOwnedKeys(newAnnotations, adoption.Annotations, nil)
+	if !labelsChanged && !annotationsChanged && !labelsRestored && !annotationsRestored {
+		return nil
+	}
+
+	reqLogger.Info("Removing the labels and annotations of the operator from namespace",
+		"Namespace", namespace.Name)
+	namespace.SetLabels(newLabels)
+	namespace.SetAnnotations(newAnnotations)
+	return client.Update(ctx, namespace)
+}
+
+// IsManagedLabel returns true when the given label is recorded as set by the
+// operator on the Namespace. The label is not necessarily present.
+func IsManagedLabel(namespace *corev1.Namespace, key string) bool {
+	for _, managed := range splitKeys(namespace.GetAnnotations()[ManagedLabelsAnnotation]) {
+		if managed == key {
+			return true
+		}
+	}
+	return false
+}
+
+// SetNamespaceLabels sets the given labels on the Namespace. When the
+// Namespace was in the state applied by the operator, the new state is
+// recorded too: ReconcileNamespace doesn't report the change as a drift.
+func SetNamespaceLabels(namespace *corev1.Namespace, labels map[string]string) {
+	annotations := namespace.GetAnnotations()
+	ownedLabels := splitKeys(annotations[ManagedLabelsAnnotation])
+	ownedAnnotations := splitKeys(annotations[ManagedAnnotationsAnnotation])
+	currentState := func() namespaceState {
+		return namespaceState{
+			Labels:      selectKeys(namespace.GetLabels(), ownedLabels),
+			Annotations: selectKeys(annotations, ownedAnnotations),
+		}
 	}
+	_, tracked := annotations[AppliedStateAnnotation]
+	inSync := tracked && !hasDrifted(annotations, currentState())
 
-	return bytes.Equal(mA, mB)
+	newLabels := map[string]string{}
+	for key, value := range namespace.GetLabels() {
+		newLabels[key] = value
+	}
+	for key, value := range labels {
+		newLabels[key] = value
+	}
+	namespace.SetLabels(newLabels)
+
+	if inSync {
+		namespace.SetAnnotations(setAppliedState(annotations, currentState()))
+	}
+}
+
+// withoutBookkeeping returns a new map made of the given annotations, minus
+// the ones used by the operator to keep track of the objects it manages
+func withoutBookkeeping(annotations map[string]string) map[string]string {
+	result := map[string]string{}
+	for key, value := range annotations {
+		switch key {
+		case AppliedStateAnnotation, ManagedLabelsAnnotation, ManagedAnnotationsAnnotation, AdoptionAnnotation:
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// reconcileOwnedKeys returns a new map made of current where: the desired
+// entries are set and the entries previously owned, but no longer desired,
+// are removed. All the other entries are preserved. The second value is true
+// when the returned map differs from current.
+func reconcileOwnedKeys(current, desired map[string]string, owned []string) (map[string]string, bool) {
+	result := map[string]string{}
+	for key, value := range current {
+		result[key] = value
+	}
+
+	changed := false
+	for _, key := range owned {
+		if _, wanted := desired[key]; wanted {
+			continue
+		}
+		if _, found := result[key]; found {
+			delete(result, key)
+			changed = true
+		}
+	}
+	for key, value := range desired {
+		if currentValue, found := result[key]; !found || currentValue != value {
+			result[key] = value
+			changed = true
+		}
+	}
+
+	return result, changed
+}
+
+// overriddenValues returns the entries of current that are going to be owned
+// by the operator once the desired ones are set
+func overriddenValues(current, desired map[string]string) map[string]string {
+	overridden := map[string]string{}
+	for key := range desired {
+		if value, found := current[key]; found {
+			overridden[key] = value
+		}
+	}
+	return overridden
+}
+
+// selectKeys returns a new map made only of the entries of m with the given
+// keys
+func selectKeys(m map[string]string, keys []string) map[string]string {
+	selected := map[string]string{}
+	for _, key := range keys {
+		if value, found := m[key]; found {
+			selected[key] = value
+		}
+	}
+	return selected
+}
+
+// joinKeys returns the sorted keys of m as a comma separated list
+func joinKeys(m map[string]string) string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// splitKeys is the opposite of joinKeys
+func splitKeys(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+// mergeAnnotations returns a new map made of the current annotations
+// overridden by the desired ones
+func mergeAnnotations(current, desired map[string]string) map[string]string {
+	merged := map[string]string{}
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key, value := range desired {
+		merged[key] = value
+	}
+	return merged
 }
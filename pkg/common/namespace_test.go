@@ -0,0 +1,121 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReconcileOwnedKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  map[string]string
+		desired  map[string]string
+		owned    []string
+		expected map[string]string
+		changed  bool
+	}{
+		{
+			name:     "nothing to do",
+			current:  map[string]string{"app": "acme"},
+			desired:  map[string]string{"app": "acme"},
+			owned:    []string{"app"},
+			expected: map[string]string{"app": "acme"},
+			changed:  false,
+		},
+		{
+			name:     "new namespace",
+			current:  nil,
+			desired:  map[string]string{"app": "acme"},
+			owned:    nil,
+			expected: map[string]string{"app": "acme"},
+			changed:  true,
+		},
+		{
+			name:     "desired value changed",
+			current:  map[string]string{"app": "acme"},
+			desired:  map[string]string{"app": "other"},
+			owned:    []string{"app"},
+			expected: map[string]string{"app": "other"},
+			changed:  true,
+		},
+		{
+			name:     "owned key no longer desired is removed",
+			current:  map[string]string{"app": "acme", "tier": "gold"},
+			desired:  map[string]string{"app": "acme"},
+			owned:    []string{"app", "tier"},
+			expected: map[string]string{"app": "acme"},
+			changed:  true,
+		},
+		{
+			name:     "keys set by someone else are preserved",
+			current:  map[string]string{"app": "acme", "team": "a"},
+			desired:  map[string]string{"app": "acme"},
+			owned:    []string{"app"},
+			expected: map[string]string{"app": "acme", "team": "a"},
+			changed:  false,
+		},
+		{
+			name:     "owned key already removed by someone else",
+			current:  map[string]string{"app": "acme"},
+			desired:  map[string]string{"app": "acme"},
+			owned:    []string{"app", "tier"},
+			expected: map[string]string{"app": "acme"},
+			changed:  false,
+		},
+		{
+			name:     "key set by someone else becomes desired",
+			current:  map[string]string{"team": "a"},
+			desired:  map[string]string{"team": "b"},
+			owned:    nil,
+			expected: map[string]string{"team": "b"},
+			changed:  true,
+		},
+		{
+			name:     "drifted owned key is restored",
+			current:  map[string]string{"app": "changed-by-hand"},
+			desired:  map[string]string{"app": "acme"},
+			owned:    []string{"app"},
+			expected: map[string]string{"app": "acme"},
+			changed:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var before map[string]string
+			if test.current != nil {
+				before = map[string]string{}
+				for key, value := range test.current {
+					before[key] = value
+				}
+			}
+
+			result, changed := reconcileOwnedKeys(test.current, test.desired, test.owned)
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+			if changed != test.changed {
+				t.Errorf("expected changed to be %v, got %v", test.changed, changed)
+			}
+			if !reflect.DeepEqual(test.current, before) {
+				t.Errorf("current has been modified: %v", test.current)
+			}
+		})
+	}
+}
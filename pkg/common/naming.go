@@ -1,27 +1,165 @@
 package common
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"fmt"
 	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Default templates of the names of the Namespaces created by the operator
+const (
+	DefaultSpaceNamespaceTemplate  = "{{.Organization}}-{{.Space}}-space"
+	DefaultSpacesNamespaceTemplate = "{{.Organization}}-spaces"
+)
+
+// SpacesNamespaceLabel is set on the Namespace holding the Space objects of
+// an Organization, its value is the name of the Organization
+const SpacesNamespaceLabel = "organization-operator.k8s.suse.com/spaces-of"
+
+// maxNamespaceNameLength is the maximum length of the name of a Namespace
+const maxNamespaceNameLength = 63
+
+// NamespaceNameData holds the values available to the templates of the names
+// of the Namespaces
+type NamespaceNameData struct {
+	Organization string
+	Space        string
+}
+
+var (
+	spaceNamespaceTemplate  = template.Must(template.New("space").Parse(DefaultSpaceNamespaceTemplate))
+	spacesNamespaceTemplate = template.Must(template.New("spaces").Parse(DefaultSpacesNamespaceTemplate))
 )
 
-// NameofNameOfNamespaceCreateBySpace returns the name of the Namespace object
+// SetNamespaceNameTemplates changes the templates used to build the names of
+// the Namespaces of the Spaces and of the Namespaces holding the Space
+// objects of the Organizations. It must be called before the controllers and
+// the webhooks are started.
+func SetNamespaceNameTemplates(spaceNamespace, spacesNamespace string) error {
+	space, err := template.New("space").Parse(spaceNamespace)
+	if err != nil {
+		return fmt.Errorf("Invalid template of the Namespace of a Space: %v", err)
+	}
+	if !dependsOn(space, "Organization") || !dependsOn(space, "Space") {
+		return fmt.Errorf("The template of the Namespace of a Space must use both the Organization and the Space names")
+	}
+
+	spaces, err := template.New("spaces").Parse(spacesNamespace)
+	if err != nil {
+		return fmt.Errorf("Invalid template of the Namespace of the Spaces of an Organization: %v", err)
+	}
+	if !dependsOn(spaces, "Organization") {
+		return fmt.Errorf("The template of the Namespace of the Spaces of an Organization must use the Organization name")
+	}
+
+	spaceNamespaceTemplate = space
+	spacesNamespaceTemplate = spaces
+	return nil
+}
+
+// dependsOn returns true when the output of the template changes with the
+// given field of NamespaceNameData
+func dependsOn(tmpl *template.Template, field string) bool {
+	first := NamespaceNameData{Organization: "org", Space: "space"}
+	second := first
+	switch field {
+	case "Organization":
+		second.Organization = "other-org"
+	case "Space":
+		second.Space = "other-space"
+	}
+
+	firstName, err := render(tmpl, first)
+	if err != nil {
+		return false
+	}
+	secondName, err := render(tmpl, second)
+	if err != nil {
+		return false
+	}
+	return firstName != secondName
+}
+
+// render executes the template, the result is shortened to be a valid
+// Namespace name
+func render(tmpl *template.Template, data NamespaceNameData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return shortenName(buf.String()), nil
+}
+
+// shortenName returns the name unchanged when it fits inside of a Namespace
+// name. Longer names are truncated and suffixed with a hash of the whole
+// name, so two long names sharing the same prefix don't clash.
+func shortenName(name string) string {
+	if len(name) <= maxNamespaceNameLength {
+		return name
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(name)))[:8]
+	prefix := strings.TrimRight(name[:maxNamespaceNameLength-len(hash)-1], "-")
+	return prefix + "-" + hash
+}
+
+// NameOfNamespaceCreateBySpace returns the name of the Namespace object
 // that is created by the SpaceController for each Space object.
 func NameOfNamespaceCreateBySpace(organizationName, spaceName string) string {
-	return fmt.Sprintf("%s-%s-space", organizationName, spaceName)
+	data := NamespaceNameData{Organization: organizationName, Space: spaceName}
+	name, err := render(spaceNamespaceTemplate, data)
+	if err != nil {
+		// Cannot happen, the template has been checked by
+		// SetNamespaceNameTemplates
+		return shortenName(fmt.Sprintf("%s-%s-space", organizationName, spaceName))
+	}
+	return name
 }
 
 // ComputeSpacesNamespaceFromOrganizationName returns the name of the Namespace
 // where all the Space objects of the given Organization are going to be created.
 func ComputeSpacesNamespaceFromOrganizationName(organization string) string {
-	return organization + "-spaces"
+	name, err := render(spacesNamespaceTemplate, NamespaceNameData{Organization: organization})
+	if err != nil {
+		// Cannot happen, the template has been checked by
+		// SetNamespaceNameTemplates
+		return shortenName(organization + "-spaces")
+	}
+	return name
 }
 
-// CompuComputeOrganizationNameFromSpaceNamespace returns the name of the
-// Organization that owns the Space.
-func ComputeOrganizationNameFromSpaceNamespace(namespace string) (string, error) {
-	if strings.HasSuffix(namespace, "-spaces") {
-		return strings.TrimSuffix(namespace, "-spaces"), nil
+// OrganizationOfSpacesNamespace returns the name of the Organization that
+// owns the Space objects defined inside of the given Namespace. The
+// Organization is found using the label set by the operator on the
+// Namespace; the label is trusted only when the name of the Namespace
+// matches the one of the Organization and the Namespace is controlled by the
+// Organization. The second value is false when the Namespace doesn't exist
+// or doesn't belong to an Organization.
+func OrganizationOfSpacesNamespace(c client.Reader, namespace string, ctx context.Context) (string, bool, error) {
+	found := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, found); err != nil {
+		if errors.IsNotFound(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	organization := found.GetLabels()[SpacesNamespaceLabel]
+	if organization == "" || ComputeSpacesNamespaceFromOrganizationName(organization) != namespace {
+		return "", false, nil
+	}
+	owner := metav1.GetControllerOf(found)
+	if owner == nil ||
+		owner.Kind != "Organization" ||
+		owner.Name != organization ||
+		!strings.HasPrefix(owner.APIVersion, organizationAPIGroup+"/") {
+		return "", false, nil
 	}
-	return "", fmt.Errorf("Unrecognized format %s", namespace)
+	return organization, true, nil
 }
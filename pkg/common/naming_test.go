@@ -0,0 +1,174 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestShortenName(t *testing.T) {
+	long := strings.Repeat("a", 70)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"short name", "acme-web-space", "acme-web-space"},
+		{"exactly 63 characters", strings.Repeat("a", 63), strings.Repeat("a", 63)},
+		{"long name", long, strings.Repeat("a", 54) + "-" + shortHash(long)},
+		{
+			"truncated on a dash",
+			strings.Repeat("a", 53) + "--" + strings.Repeat("b", 20),
+			strings.Repeat("a", 53) + "-" + shortHash(strings.Repeat("a", 53)+"--"+strings.Repeat("b", 20)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := shortenName(test.input)
+			if name != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, name)
+			}
+			for _, msg := range validation.IsDNS1123Label(name) {
+				t.Errorf("%q is not a valid Namespace name: %s", name, msg)
+			}
+		})
+	}
+
+	// Long names sharing the same prefix must not clash
+	first := shortenName(long + "-first")
+	second := shortenName(long + "-second")
+	if first == second {
+		t.Errorf("%q is produced by two different names", first)
+	}
+}
+
+// shortHash returns the hash suffix appended by shortenName
+func shortHash(name string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(name)))[:8]
+}
+
+func TestSetNamespaceNameTemplates(t *testing.T) {
+	defer func() {
+		if err := SetNamespaceNameTemplates(DefaultSpaceNamespaceTemplate, DefaultSpacesNamespaceTemplate); err != nil {
+			t.Fatalf("cannot restore the default templates: %v", err)
+		}
+	}()
+
+	tests := []struct {
+		name            string
+		spaceTemplate   string
+		spacesTemplate  string
+		valid           bool
+		spaceNamespace  string
+		spacesNamespace string
+	}{
+		{
+			name:            "defaults",
+			spaceTemplate:   DefaultSpaceNamespaceTemplate,
+			spacesTemplate:  DefaultSpacesNamespaceTemplate,
+			valid:           true,
+			spaceNamespace:  "acme-web-space",
+			spacesNamespace: "acme-spaces",
+		},
+		{
+			name:            "custom templates",
+			spaceTemplate:   "{{.Space}}.{{.Organization}}",
+			spacesTemplate:  "org-{{.Organization}}",
+			valid:           true,
+			spaceNamespace:  "web.acme",
+			spacesNamespace: "org-acme",
+		},
+		{
+			name:           "space template without the Space",
+			spaceTemplate:  "{{.Organization}}-space",
+			spacesTemplate: DefaultSpacesNamespaceTemplate,
+		},
+		{
+			name:           "space template without the Organization",
+			spaceTemplate:  "{{.Space}}-space",
+			spacesTemplate: DefaultSpacesNamespaceTemplate,
+		},
+		{
+			name:           "spaces template without the Organization",
+			spaceTemplate:  DefaultSpaceNamespaceTemplate,
+			spacesTemplate: "spaces",
+		},
+		{
+			name:           "malformed template",
+			spaceTemplate:  "{{.Organization}-{{.Space}}",
+			spacesTemplate: DefaultSpacesNamespaceTemplate,
+		},
+		{
+			name:           "unknown field",
+			spaceTemplate:  "{{.Organization}}-{{.Team}}",
+			spacesTemplate: DefaultSpacesNamespaceTemplate,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := SetNamespaceNameTemplates(DefaultSpaceNamespaceTemplate, DefaultSpacesNamespaceTemplate); err != nil {
+				t.Fatalf("cannot set the default templates: %v", err)
+			}
+
+			err := SetNamespaceNameTemplates(test.spaceTemplate, test.spacesTemplate)
+			if !test.valid {
+				if err == nil {
+					t.Fatalf("expected the templates to be rejected")
+				}
+				// The previous templates are still in use
+				if name := NameOfNamespaceCreateBySpace("acme", "web"); name != "acme-web-space" {
+					t.Errorf("the default template has been replaced: got %q", name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if name := NameOfNamespaceCreateBySpace("acme", "web"); name != test.spaceNamespace {
+				t.Errorf("expected Namespace of the Space %q, got %q", test.spaceNamespace, name)
+			}
+			if name := ComputeSpacesNamespaceFromOrganizationName("acme"); name != test.spacesNamespace {
+				t.Errorf("expected Namespace of the Spaces %q, got %q", test.spacesNamespace, name)
+			}
+		})
+	}
+}
+
+func TestLongNamespaceNames(t *testing.T) {
+	organization := strings.Repeat("o", 40)
+	space := strings.Repeat("s", 40)
+
+	for _, name := range []string{
+		NameOfNamespaceCreateBySpace(organization, space),
+		ComputeSpacesNamespaceFromOrganizationName(organization + organization),
+	} {
+		for _, msg := range validation.IsDNS1123Label(name) {
+			t.Errorf("%q is not a valid Namespace name: %s", name, msg)
+		}
+	}
+	if NameOfNamespaceCreateBySpace(organization, space) == NameOfNamespaceCreateBySpace(organization, space+"x") {
+		t.Errorf("two Spaces share the same Namespace")
+	}
+}
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"context"
+
+	logr "github.com/go-logr/logr"
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// ReconcileNetworkPolicy ensures the given NetworkPolicy exists and has the
+// right spec. It returns the operation performed against the cluster,
+// OperationResultDriftCorrected is returned when the NetworkPolicy has been
+// changed by someone else.
+func ReconcileNetworkPolicy(
+	client client.Client,
+	networkPolicy *networking.NetworkPolicy,
+	owner metav1.Object,
+	scheme *runtime.Scheme,
+	reqLogger logr.Logger,
+	ctx context.Context) (controllerutil.OperationResult, error) {
+	if owner != nil && scheme != nil {
+		if err := controllerutil.SetControllerReference(owner, networkPolicy, scheme); err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+	}
+
+	networkPolicy.SetAnnotations(setAppliedState(networkPolicy.GetAnnotations(), networkPolicy.Spec))
+
+	// Check if this NetworkPolicy already exists
+	found := &networking.NetworkPolicy{}
+	err := client.Get(
+		ctx,
+		types.NamespacedName{
+			Name:      networkPolicy.Name,
+			Namespace: networkPolicy.Namespace,
+		},
+		found)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			reqLogger.Info(
+				"Creating a new NetworkPolicy",
+				"Namespace", networkPolicy.Namespace,
+				"Name", networkPolicy.Name)
+			return createResult(client.Create(ctx, networkPolicy))
+		}
+		return controllerutil.OperationResultNone, err
+	}
+
+	if !equality.Semantic.DeepEqual(found.Spec, networkPolicy.Spec) {
+		drifted := hasDrifted(found.GetAnnotations(), found.Spec)
+		reqLogger.Info(
+			"Updating NetworkPolicy to have the right spec",
+			"Namespace", found.Namespace,
+			"Name", found.Name,
+			"Drifted", drifted)
+		found.Spec = networkPolicy.Spec
+		found.SetAnnotations(setAppliedState(found.GetAnnotations(), networkPolicy.Spec))
+		return updateResult(drifted, client.Update(ctx, found))
+	}
+
+	return controllerutil.OperationResultNone, nil
+}
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// AppliedStateAnnotation holds a hash of the state the operator applied the
+// last time it created or updated one of the objects it manages. It allows
+// to tell apart changes of the desired state from changes made by someone
+// else.
+const AppliedStateAnnotation = "organization-operator.k8s.suse.com/applied-state"
+
+// OperationResultDriftCorrected means that an existing resource has been
+// changed by someone else and it has been updated to match the desired state
+// again
+const OperationResultDriftCorrected controllerutil.OperationResult = "driftCorrected"
+
+// createResult returns the outcome of a Create call
+func createResult(err error) (controllerutil.OperationResult, error) {
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	return controllerutil.OperationResultCreated, nil
+}
+
+// updateResult returns the outcome of an Update call. drifted tells whether
+// the object had been changed by someone else.
+func updateResult(drifted bool, err error) (controllerutil.OperationResult, error) {
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	if drifted {
+		return OperationResultDriftCorrected, nil
+	}
+	return controllerutil.OperationResultUpdated, nil
+}
+
+// hashOfState returns the hash of the given state, which is stored inside of
+// the AppliedStateAnnotation
+func hashOfState(state interface{}) string {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// hasDrifted returns true when the current state of an object doesn't match
+// the one the operator applied the last time. Objects without the
+// AppliedStateAnnotation are never considered drifted.
+func hasDrifted(annotations map[string]string, currentState interface{}) bool {
+	applied, found := annotations[AppliedStateAnnotation]
+	if !found || applied == "" {
+		return false
+	}
+	return applied != hashOfState(currentState)
+}
+
+// setAppliedState records the hash of the given state inside of annotations
+func setAppliedState(annotations map[string]string, state interface{}) map[string]string {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AppliedStateAnnotation] = hashOfState(state)
+	return annotations
+}
@@ -0,0 +1,158 @@
+package common
+
+import (
+	"context"
+
+	logr "github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// ReconcileResourceQuota ensures the given ResourceQuota exists and has the
+// right spec. It returns the operation performed against the cluster,
+// OperationResultDriftCorrected is returned when the ResourceQuota has been
+// changed by someone else.
+func ReconcileResourceQuota(
+	client client.Client,
+	quota *corev1.ResourceQuota,
+	owner metav1.Object,
+	scheme *runtime.Scheme,
+	reqLogger logr.Logger,
+	ctx context.Context) (controllerutil.OperationResult, error) {
+	if owner != nil && scheme != nil {
+		if err := controllerutil.SetControllerReference(owner, quota, scheme); err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+	}
+
+	quota.SetAnnotations(setAppliedState(quota.GetAnnotations(), quota.Spec))
+
+	// Check if this ResourceQuota already exists
+	found := &corev1.ResourceQuota{}
+	err := client.Get(
+		ctx,
+		types.NamespacedName{
+			Name:      quota.Name,
+			Namespace: quota.Namespace,
+		},
+		found)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			reqLogger.Info(
+				"Creating a new ResourceQuota",
+				"Namespace", quota.Namespace,
+				"Name", quota.Name,
+				"Hard", quota.Spec.Hard)
+			return createResult(client.Create(ctx, quota))
+		}
+		return controllerutil.OperationResultNone, err
+	}
+
+	if !equality.Semantic.DeepEqual(found.Spec, quota.Spec) {
+		drifted := hasDrifted(found.GetAnnotations(), found.Spec)
+		reqLogger.Info(
+			"Updating ResourceQuota to have the right spec",
+			"Namespace", found.Namespace,
+			"Name", found.Name,
+			"Drifted", drifted)
+		found.Spec = quota.Spec
+		found.SetAnnotations(setAppliedState(found.GetAnnotations(), quota.Spec))
+		return updateResult(drifted, client.Update(ctx, found))
+	}
+
+	return controllerutil.OperationResultNone, nil
+}
+
+// ReconcileLimitRange ensures the given LimitRange exists and has the right
+// spec. It returns the operation performed against the cluster,
+// OperationResultDriftCorrected is returned when the LimitRange has been
+// changed by someone else.
+func ReconcileLimitRange(
+	client client.Client,
+	limitRange *corev1.LimitRange,
+	owner metav1.Object,
+	scheme *runtime.Scheme,
+	reqLogger logr.Logger,
+	ctx context.Context) (controllerutil.OperationResult, error) {
+	if owner != nil && scheme != nil {
+		if err := controllerutil.SetControllerReference(owner, limitRange, scheme); err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+	}
+
+	defaultLimitRangeSpec(&limitRange.Spec)
+	limitRange.SetAnnotations(setAppliedState(limitRange.GetAnnotations(), limitRange.Spec))
+
+	// Check if this LimitRange already exists
+	found := &corev1.LimitRange{}
+	err := client.Get(
+		ctx,
+		types.NamespacedName{
+			Name:      limitRange.Name,
+			Namespace: limitRange.Namespace,
+		},
+		found)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			reqLogger.Info(
+				"Creating a new LimitRange",
+				"Namespace", limitRange.Namespace,
+				"Name", limitRange.Name)
+			return createResult(client.Create(ctx, limitRange))
+		}
+		return controllerutil.OperationResultNone, err
+	}
+
+	if !equality.Semantic.DeepEqual(found.Spec, limitRange.Spec) {
+		drifted := hasDrifted(found.GetAnnotations(), found.Spec)
+		reqLogger.Info(
+			"Updating LimitRange to have the right spec",
+			"Namespace", found.Namespace,
+			"Name", found.Name,
+			"Drifted", drifted)
+		found.Spec = limitRange.Spec
+		found.SetAnnotations(setAppliedState(found.GetAnnotations(), limitRange.Spec))
+		return updateResult(drifted, client.Update(ctx, found))
+	}
+
+	return controllerutil.OperationResultNone, nil
+}
+
+// defaultLimitRangeSpec applies to the Container entries of the spec the same
+// defaults set by the API server, otherwise the LimitRange stored by the
+// cluster would never match the desired one
+func defaultLimitRangeSpec(spec *corev1.LimitRangeSpec) {
+	for i := range spec.Limits {
+		item := &spec.Limits[i]
+		if item.Type != corev1.LimitTypeContainer {
+			continue
+		}
+		// The default limit is the max, when not specified
+		for name, quantity := range item.Max {
+			if _, found := item.Default[name]; !found {
+				if item.Default == nil {
+					item.Default = corev1.ResourceList{}
+				}
+				item.Default[name] = quantity.DeepCopy()
+			}
+		}
+		// The default request is the default limit or the min, when not
+		// specified
+		for _, source := range []corev1.ResourceList{item.Default, item.Min} {
+			for name, quantity := range source {
+				if _, found := item.DefaultRequest[name]; !found {
+					if item.DefaultRequest == nil {
+						item.DefaultRequest = corev1.ResourceList{}
+					}
+					item.DefaultRequest[name] = quantity.DeepCopy()
+				}
+			}
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	logr "github.com/go-logr/logr"
 	rbac "k8s.io/api/rbac/v1"
@@ -15,7 +17,27 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
-func NewRoleBinding(name, namespace string, users, groups []string, roleRef rbac.RoleRef) *rbac.RoleBinding {
+// MergeMembers returns a new list made of all the given lists of users or
+// groups. Duplicated entries are removed.
+func MergeMembers(lists ...[]string) []string {
+	merged := []string{}
+	seen := map[string]bool{}
+	for _, list := range lists {
+		for _, member := range list {
+			if !seen[member] {
+				seen[member] = true
+				merged = append(merged, member)
+			}
+		}
+	}
+	return merged
+}
+
+// NewRoleBinding returns a RoleBinding granting roleRef to the given users,
+// groups and ServiceAccounts. ServiceAccounts are expressed in the
+// "namespace/name" format, the namespace of the RoleBinding is used when the
+// namespace is omitted.
+func NewRoleBinding(name, namespace string, users, groups, serviceAccounts []string, roleRef rbac.RoleRef) *rbac.RoleBinding {
 	subjects := []rbac.Subject{}
 	for _, groupName := range groups {
 		subject := rbac.Subject{
@@ -33,6 +55,7 @@ func NewRoleBinding(name, namespace string, users, groups []string, roleRef rbac
 		}
 		subjects = append(subjects, subject)
 	}
+	subjects = append(subjects, serviceAccountSubjects(serviceAccounts, namespace)...)
 
 	return &rbac.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
@@ -44,20 +67,47 @@ func NewRoleBinding(name, namespace string, users, groups []string, roleRef rbac
 	}
 }
 
+// serviceAccountSubjects returns the RBAC subjects of the given
+// ServiceAccounts, expressed in the "namespace/name" format. defaultNamespace
+// is used for the ServiceAccounts without a namespace.
+func serviceAccountSubjects(serviceAccounts []string, defaultNamespace string) []rbac.Subject {
+	subjects := []rbac.Subject{}
+	for _, serviceAccount := range serviceAccounts {
+		namespace := defaultNamespace
+		name := serviceAccount
+		if parts := strings.SplitN(serviceAccount, "/", 2); len(parts) == 2 {
+			namespace = parts[0]
+			name = parts[1]
+		}
+		subjects = append(subjects, rbac.Subject{
+			Kind:      rbac.ServiceAccountKind,
+			Name:      name,
+			Namespace: namespace,
+		})
+	}
+	return subjects
+}
+
+// ReconcileRBACRoleBinding ensures the given RoleBinding exists and has the
+// right set of Subjects and RoleRef. It returns the operation performed
+// against the cluster, OperationResultDriftCorrected is returned when the
+// RoleBinding has been changed by someone else.
 func ReconcileRBACRoleBinding(
 	client client.Client,
 	roleBinding *rbac.RoleBinding,
 	owner metav1.Object,
 	scheme *runtime.Scheme,
 	reqLogger logr.Logger,
-	ctx context.Context) error {
+	ctx context.Context) (controllerutil.OperationResult, error) {
 	// Set Organization instance as the owner and controller
 	if owner != nil && scheme != nil {
 		if err := controllerutil.SetControllerReference(owner, roleBinding, scheme); err != nil {
-			return err
+			return controllerutil.OperationResultNone, err
 		}
 	}
 
+	roleBinding.SetAnnotations(setAppliedState(roleBinding.GetAnnotations(), roleBindingState(roleBinding)))
+
 	// Check if this RBAC RoleBinding already exists
 	found := &rbac.RoleBinding{}
 	err := client.Get(
@@ -75,66 +125,105 @@ func ReconcileRBACRoleBinding(
 				"Name", roleBinding.Name,
 				"Subjects", roleBinding.Subjects,
 				"RoleRef", roleBinding.RoleRef)
-			return client.Create(ctx, roleBinding)
+			return createResult(client.Create(ctx, roleBinding))
 		}
-		return err
+		return controllerutil.OperationResultNone, err
 	}
 
 	if !areRoleBindingsEqual(found, roleBinding) {
+		drifted := hasDrifted(found.GetAnnotations(), roleBindingState(found))
 		reqLogger.Info(
 			"Updating RBAC RoleBinding to have right set of Subjects and RoleRefs",
 			"Name", found.Name,
-			"Namespace", found.Namespace)
+			"Namespace", found.Namespace,
+			"Drifted", drifted)
+		if found.RoleRef != roleBinding.RoleRef {
+			// The RoleRef of a RoleBinding cannot be changed: the
+			// RoleBinding has to be recreated
+			reqLogger.Info(
+				"Recreating RBAC RoleBinding to change its RoleRef",
+				"Name", found.Name,
+				"Namespace", found.Namespace,
+				"OldRoleRef", found.RoleRef,
+				"RoleRef", roleBinding.RoleRef)
+			return updateResult(drifted, replaceRoleBinding(client, found, roleBinding, ctx))
+		}
 		found.Subjects = roleBinding.Subjects
-		found.RoleRef = roleBinding.RoleRef
-		return client.Update(ctx, found)
+		found.SetAnnotations(setAppliedState(found.GetAnnotations(), roleBindingState(roleBinding)))
+		return updateResult(drifted, client.Update(ctx, found))
 	}
 
-	return nil
+	return controllerutil.OperationResultNone, nil
 }
 
-func ArePolicyRulesEqual(a, b []rbac.PolicyRule) bool {
-	mA, err := json.Marshal(a)
-	if err != nil {
-		return false
+// replaceRoleBinding replaces the existing RoleBinding with the desired one,
+// which has a different RoleRef. The subjects never lose their access: a
+// temporary copy of the desired RoleBinding is created before the existing
+// one is deleted, and it is removed only once the desired RoleBinding
+// exists. When the replacement fails the temporary copy is left behind, it
+// carries the same labels of the desired RoleBinding.
+func replaceRoleBinding(client client.Client, existing, desired *rbac.RoleBinding, ctx context.Context) error {
+	temporary := desired.DeepCopy()
+	temporary.Name = fmt.Sprintf("%s-%s", desired.Name, hashOfState(desired.RoleRef)[:8])
+	if err := client.Create(ctx, temporary); err != nil && !errors.IsAlreadyExists(err) {
+		return err
 	}
 
-	mB, err := json.Marshal(b)
-	if err != nil {
-		return false
+	if err := client.Delete(ctx, existing); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err := client.Create(ctx, desired); err != nil {
+		return err
 	}
 
-	return bytes.Equal(mA, mB)
+	if err := client.Delete(ctx, temporary); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
 }
 
-func areRoleBindingsEqual(a, b *rbac.RoleBinding) bool {
-	dataA, err := json.Marshal(a.Subjects)
-	if err != nil {
-		return false
+// roleBindingState returns the part of a RoleBinding managed by the operator
+func roleBindingState(roleBinding *rbac.RoleBinding) interface{} {
+	subjects := roleBinding.Subjects
+	if len(subjects) == 0 {
+		// the API server doesn't make any distinction between an empty
+		// and a nil list
+		subjects = nil
 	}
 
-	dataB, err := json.Marshal(b.Subjects)
-	if err != nil {
-		return false
+	return struct {
+		Subjects []rbac.Subject
+		RoleRef  rbac.RoleRef
+	}{
+		Subjects: subjects,
+		RoleRef:  roleBinding.RoleRef,
 	}
+}
 
-	if !bytes.Equal(dataA, dataB) {
+func ArePolicyRulesEqual(a, b []rbac.PolicyRule) bool {
+	mA, err := json.Marshal(a)
+	if err != nil {
 		return false
 	}
 
-	dataA, err = json.Marshal(a.RoleRef)
+	mB, err := json.Marshal(b)
 	if err != nil {
 		return false
 	}
 
-	dataB, err = json.Marshal(b.RoleRef)
+	return bytes.Equal(mA, mB)
+}
+
+func areRoleBindingsEqual(a, b *rbac.RoleBinding) bool {
+	dataA, err := json.Marshal(roleBindingState(a))
 	if err != nil {
 		return false
 	}
 
-	if !bytes.Equal(dataA, dataB) {
+	dataB, err := json.Marshal(roleBindingState(b))
+	if err != nil {
 		return false
 	}
 
-	return true
+	return bytes.Equal(dataA, dataB)
 }
@@ -0,0 +1,185 @@
+/*
+Copyright 2020 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	rbac "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// recordingClient records the objects created and deleted through it, the
+// creation of the object named failCreate fails
+type recordingClient struct {
+	client.Client
+	operations []string
+	failCreate string
+}
+
+func (c *recordingClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	c.operations = append(c.operations, "create "+accessor.GetName())
+	if accessor.GetName() == c.failCreate {
+		return fmt.Errorf("cannot create %s", accessor.GetName())
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *recordingClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	c.operations = append(c.operations, "delete "+accessor.GetName())
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func clusterRoleRef(name string) rbac.RoleRef {
+	return rbac.RoleRef{APIGroup: rbac.GroupName, Kind: "ClusterRole", Name: name}
+}
+
+func TestReconcileRBACRoleBindingReplacesRoleRef(t *testing.T) {
+	existing := NewRoleBinding("editors", "acme-web-space", []string{"alice"}, nil, nil, clusterRoleRef("edit"))
+	c := &recordingClient{Client: fake.NewFakeClientWithScheme(scheme.Scheme, existing)}
+	ctx := context.Background()
+
+	desired := NewRoleBinding("editors", "acme-web-space", []string{"alice"}, nil, nil, clusterRoleRef("hardened-edit"))
+	result, err := ReconcileRBACRoleBinding(c, desired, nil, nil, logf.NullLogger{}, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != controllerutil.OperationResultUpdated {
+		t.Errorf("expected result %s, got %s", controllerutil.OperationResultUpdated, result)
+	}
+
+	// alice never loses access: the temporary copy exists while the
+	// RoleBinding is recreated
+	temporary := "editors-" + hashOfState(desired.RoleRef)[:8]
+	expected := []string{
+		"create " + temporary,
+		"delete editors",
+		"create editors",
+		"delete " + temporary,
+	}
+	if !reflect.DeepEqual(c.operations, expected) {
+		t.Errorf("expected operations %v, got %v", expected, c.operations)
+	}
+
+	found := &rbac.RoleBinding{}
+	if err = c.Get(ctx, client.ObjectKey{Name: "editors", Namespace: "acme-web-space"}, found); err != nil {
+		t.Fatal(err)
+	}
+	if found.RoleRef != desired.RoleRef {
+		t.Errorf("expected RoleRef %v, got %v", desired.RoleRef, found.RoleRef)
+	}
+	err = c.Get(ctx, client.ObjectKey{Name: temporary, Namespace: "acme-web-space"}, found)
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the temporary RoleBinding to be deleted, got %v", err)
+	}
+}
+
+func TestReconcileRBACRoleBindingKeepsTemporaryCopy(t *testing.T) {
+	existing := NewRoleBinding("editors", "acme-web-space", []string{"alice"}, nil, nil, clusterRoleRef("edit"))
+	c := &recordingClient{
+		Client:     fake.NewFakeClientWithScheme(scheme.Scheme, existing),
+		failCreate: "editors",
+	}
+	ctx := context.Background()
+
+	desired := NewRoleBinding("editors", "acme-web-space", []string{"alice"}, nil, nil, clusterRoleRef("hardened-edit"))
+	desired.SetLabels(map[string]string{"app": "acme"})
+	if _, err := ReconcileRBACRoleBinding(c, desired, nil, nil, logf.NullLogger{}, ctx); err == nil {
+		t.Fatal("expected the replacement to fail")
+	}
+
+	// The temporary copy still grants access and can be found by its labels
+	temporary := &rbac.RoleBinding{}
+	err := c.Get(ctx, client.ObjectKey{
+		Name:      "editors-" + hashOfState(desired.RoleRef)[:8],
+		Namespace: "acme-web-space",
+	}, temporary)
+	if err != nil {
+		t.Fatalf("expected the temporary RoleBinding to be kept: %v", err)
+	}
+	if !reflect.DeepEqual(temporary.Subjects, desired.Subjects) || temporary.RoleRef != desired.RoleRef {
+		t.Errorf("expected the temporary RoleBinding to match %v, got %v", desired, temporary)
+	}
+	if !reflect.DeepEqual(temporary.GetLabels(), desired.GetLabels()) {
+		t.Errorf("expected labels %v, got %v", desired.GetLabels(), temporary.GetLabels())
+	}
+}
+
+func TestNewRoleBinding(t *testing.T) {
+	roleBinding := NewRoleBinding("editors", "acme-web-space",
+		[]string{"alice"},
+		[]string{"developers"},
+		[]string{"ci/deployer", "builder"},
+		clusterRoleRef("edit"))
+
+	expected := []rbac.Subject{
+		{Kind: rbac.GroupKind, Name: "developers", APIGroup: rbac.GroupName},
+		{Kind: rbac.UserKind, Name: "alice", APIGroup: rbac.GroupName},
+		{Kind: rbac.ServiceAccountKind, Name: "deployer", Namespace: "ci"},
+		// The namespace of the RoleBinding is used when omitted
+		{Kind: rbac.ServiceAccountKind, Name: "builder", Namespace: "acme-web-space"},
+	}
+	if !reflect.DeepEqual(roleBinding.Subjects, expected) {
+		t.Errorf("expected subjects %v, got %v", expected, roleBinding.Subjects)
+	}
+}
+
+func TestServiceAccountSubjects(t *testing.T) {
+	tests := []struct {
+		serviceAccount string
+		namespace      string
+		name           string
+	}{
+		{"ci/deployer", "ci", "deployer"},
+		{"deployer", "acme-web-space", "deployer"},
+		// Only the first slash separates the namespace from the name,
+		// the webhooks reject the names that are not valid
+		{"ci/deployer/extra", "ci", "deployer/extra"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.serviceAccount, func(t *testing.T) {
+			subjects := serviceAccountSubjects([]string{test.serviceAccount}, "acme-web-space")
+			expected := []rbac.Subject{{
+				Kind:      rbac.ServiceAccountKind,
+				Name:      test.name,
+				Namespace: test.namespace,
+			}}
+			if !reflect.DeepEqual(subjects, expected) {
+				t.Errorf("expected %v, got %v", expected, subjects)
+			}
+		})
+	}
+}